@@ -0,0 +1,199 @@
+package main
+
+import (
+	"math"
+
+	"example.com/drbreakboard"
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ticks for a topped out board to go dark from the bottom up
+const topOutAnimationTicks = 60
+
+// cleared pills blink on and off this often
+const clearFlashTicks = 2
+
+// one space moving, fading or flashing
+type spaceAnimation struct {
+	kind   match.MatchEventKind
+	space  playfieldState // what's drawn, only used for clears since the space is gone from the board
+	y      int
+	x      int
+	age    int
+	length int
+}
+
+// animations for one board, fed by match events
+type boardAnimations struct {
+	moves     map[[2]int]*spaceAnimation // falls and garbage by y, x, drawn with the space
+	clears    []*spaceAnimation
+	topOutAge int // -1 while the board is still playing
+}
+
+func newBoardAnimations() boardAnimations {
+	return boardAnimations{moves: map[[2]int]*spaceAnimation{}, topOutAge: -1}
+}
+
+func (viz *playfieldViz) AddMatchEvent(event match.MatchEvent) {
+	if event.Kind == match.TopOutMatchEvent {
+		viz.animations.topOutAge = 0
+		return
+	}
+
+	for _, bs := range event.Spaces {
+		anim := &spaceAnimation{kind: event.Kind, y: bs.Y, x: bs.X, length: event.Ticks}
+		if anim.length < 1 {
+			anim.length = 1
+		}
+
+		if event.Kind != match.ClearMatchEvent {
+			viz.animations.moves[[2]int{bs.Y, bs.X}] = anim
+			continue
+		}
+
+		anim.space.space = bs.Space
+		switch bs.Space.Content {
+		case drbreakboard.Virus:
+			anim.space.image = viz.getVirusImage(bs.Space)
+		case drbreakboard.Pill:
+			anim.space.image, _ = viz.getPillImage(bs.Space)
+		}
+		if anim.space.image != nil {
+			viz.animations.clears = append(viz.animations.clears, anim)
+		}
+	}
+}
+
+// hand this tick's match events to the boards and move their animations along
+func (g *Game) animateMatchEvents() {
+	for _, event := range g.matchDriver.TakeEvents() {
+		if event.PlayerIndex < len(g.playfieldViz) {
+			g.playfieldViz[event.PlayerIndex].AddMatchEvent(event)
+		}
+	}
+
+	for _, pv := range g.playfieldViz {
+		pv.TickAnimations()
+	}
+}
+
+// true once every board's animations have finished, including a full top out
+func (g *Game) animationsDone() bool {
+	for _, pv := range g.playfieldViz {
+		if !pv.AnimationsDone() {
+			return false
+		}
+	}
+	return true
+}
+
+// move every animation on a tick, call once per match tick
+func (viz *playfieldViz) TickAnimations() {
+	for key, anim := range viz.animations.moves {
+		anim.age++
+		if anim.age >= anim.length {
+			delete(viz.animations.moves, key)
+		}
+	}
+
+	clears := viz.animations.clears[:0]
+	for _, anim := range viz.animations.clears {
+		anim.age++
+		if anim.age < anim.length {
+			clears = append(clears, anim)
+		}
+	}
+	viz.animations.clears = clears
+
+	if viz.animations.topOutAge >= 0 && viz.animations.topOutAge < topOutAnimationTicks {
+		viz.animations.topOutAge++
+	}
+}
+
+// nothing still moving, clearing or darkening
+func (viz *playfieldViz) AnimationsDone() bool {
+	topOutDone := viz.animations.topOutAge < 0 || viz.animations.topOutAge >= topOutAnimationTicks
+	return len(viz.animations.moves) == 0 && len(viz.animations.clears) == 0 && topOutDone
+}
+
+// drop everything, for a new match
+func (viz *playfieldViz) ClearAnimations() {
+	viz.animations = newBoardAnimations()
+}
+
+// rows to shift the space at y, x up by and how opaque to draw it
+// falling spaces slide down from the row above, garbage also fades in from above the board
+func (viz *playfieldViz) spaceMotion(y int, x int) (float64, float64) {
+	anim, moving := viz.animations.moves[[2]int{y, x}]
+	if !moving {
+		return 0, 1
+	}
+
+	progress := float64(anim.age) / float64(anim.length)
+	if anim.kind == match.GarbageMatchEvent {
+		return 1 - progress, progress
+	}
+	return 1 - progress, 1
+}
+
+// cleared viruses fade out, cleared pills blink as they fade
+func (viz *playfieldViz) drawClearAnimations(image *ebiten.Image, xBlockPx float64, yBlockPx float64) {
+	for _, anim := range viz.animations.clears {
+		alpha := 1 - float64(anim.age)/float64(anim.length)
+		if anim.space.space.Content == drbreakboard.Pill && (anim.age/clearFlashTicks)%2 == 1 {
+			continue
+		}
+
+		left := float64(anim.x)*xBlockPx + float64(viz.xOffset+viz.xBuffer)
+		top := float64(anim.y)*yBlockPx + float64(viz.yOffset)
+		drawBoardSpace(image, anim.space, xBlockPx, yBlockPx, left, top, alpha)
+	}
+}
+
+// darken the board a row at a time from the bottom once it's topped out
+func (viz *playfieldViz) drawTopOut(image *ebiten.Image, yBlockPx float64) {
+	if viz.animations.topOutAge < 0 {
+		return
+	}
+
+	rows := len(viz.fieldState)
+	covered := math.Ceil(float64(rows*viz.animations.topOutAge) / topOutAnimationTicks)
+
+	pxImage := viz.atlas.Sprite("border")
+	geom := stretchedGeoM(pxImage, float64(viz.xPixelSize), covered*yBlockPx)
+	geom.Translate(float64(viz.xOffset+viz.xBuffer), float64(viz.yOffset)+float64(rows)*yBlockPx-covered*yBlockPx)
+
+	colorM := ebiten.ColorM{}
+	colorM.Scale(0, 0, 0, 0.6)
+	image.DrawImage(pxImage, &ebiten.DrawImageOptions{GeoM: geom, ColorM: colorM})
+}
+
+// draw a board space with its top left corner at left, top
+func drawBoardSpace(image *ebiten.Image, space playfieldState, xBlockPx float64, yBlockPx float64,
+	left float64, top float64, alpha float64) {
+	geom := ebiten.GeoM{}
+
+	// scale to size
+	imgX, imgY := space.image.Size()
+	geom.Scale(xBlockPx/float64(imgX), yBlockPx/float64(imgY))
+
+	if space.space.Linkage != drbreakboard.Unlinked {
+		geom.Translate(xBlockPx/-2, yBlockPx/-2)
+		switch space.space.Linkage {
+		case drbreakboard.Left:
+			geom.Rotate(math.Pi / 2)
+		case drbreakboard.Up:
+			geom.Rotate(math.Pi)
+		case drbreakboard.Right:
+			geom.Rotate(-math.Pi / 2)
+		}
+		geom.Translate(xBlockPx/2, yBlockPx/2)
+	}
+
+	geom.Translate(left, top)
+
+	colorM := ebiten.ColorM{}
+	colorM.Scale(1, 1, 1, alpha)
+	image.DrawImage(space.image, &ebiten.DrawImageOptions{GeoM: geom, ColorM: colorM})
+}
@@ -0,0 +1,87 @@
+// Package assets holds the images and font built into the game and its tools.
+package assets
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// the default images are built in so the game runs from any directory
+//
+//go:embed img/*.png
+var embeddedAssets embed.FS
+
+// the text font, themes can bring their own
+//
+//go:embed fonts/Arimo-Regular.ttf
+var BaseTextTTF []byte
+
+const embeddedImageDir = "img"
+
+// built-in sprites and the files they're loaded from
+var builtinImageFiles = map[string]string{
+	"redVirus":     "redVirus.png",
+	"blueVirus":    "blueVirus.png",
+	"yellowVirus":  "yellowVirus.png",
+	"redSingle":    "redSingle.png",
+	"blueSingle":   "blueSingle.png",
+	"yellowSingle": "yellowSingle.png",
+	"redLinked":    "redLinked.png",
+	"blueLinked":   "blueLinked.png",
+	"yellowLinked": "yellowLinked.png",
+	"border":       "greenPixel.png",
+}
+
+// load the built-in images, taking any found in overrideDir instead
+// overrideDir can be empty to use only the embedded images
+func LoadBuiltinImages(overrideDir string) (map[string]image.Image, error) {
+	images := map[string]image.Image{}
+	failures := make([]string, 0)
+
+	for sprite, file := range builtinImageFiles {
+		img, err := loadAssetImage(overrideDir, file)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		images[sprite] = img
+	}
+
+	if len(failures) > 0 {
+		return images, errors.New("could not load images: " + strings.Join(failures, "; "))
+	}
+	return images, nil
+}
+
+// an image from overrideDir if it's there, otherwise the embedded copy
+func loadAssetImage(overrideDir string, file string) (image.Image, error) {
+	if overrideDir != "" {
+		img, err := DecodeImageFile(os.DirFS(overrideDir), file)
+		if err == nil {
+			return img, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("override: %w", err)
+		}
+	}
+
+	return DecodeImageFile(embeddedAssets, path.Join(embeddedImageDir, file))
+}
+
+func DecodeImageFile(fsys fs.FS, file string) (image.Image, error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
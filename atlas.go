@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// transparent pixels between sprites so scaled draws don't pick up their neighbours
+const atlasPadding = 1
+
+// sprites bigger than this, like backgrounds, get an image of their own
+const atlasMaxSpriteSize = 256
+
+// rows wrap at this width so the atlas stays inside texture size limits
+const atlasMaxWidth = 2048
+
+// every small sprite packed into one image
+// draws from one texture in a row get batched by ebiten, and nothing is uploaded after the build
+type spriteAtlas struct {
+	image   *ebiten.Image
+	sprites map[string]*ebiten.Image // sub-images of the atlas, or their own image when too big
+}
+
+func NewSpriteAtlas(images imageMap) *spriteAtlas {
+	sa := &spriteAtlas{}
+	sa.Rebuild(images)
+	return sa
+}
+
+// pack the images again, for a new theme
+// anything holding the atlas sees the new sprites
+func (sa *spriteAtlas) Rebuild(images imageMap) {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sa.sprites = map[string]*ebiten.Image{}
+
+	// lay the small sprites out in rows, each as tall as its tallest sprite
+	rects := make(map[string]image.Rectangle, len(names))
+	x, y, rowHeight, width := atlasPadding, atlasPadding, 0, atlasPadding
+	for _, name := range names {
+		size := images[name].Bounds().Size()
+		if size.X > atlasMaxSpriteSize || size.Y > atlasMaxSpriteSize {
+			sa.sprites[name] = ebiten.NewImageFromImage(images[name])
+			continue
+		}
+
+		if x+size.X+atlasPadding > atlasMaxWidth {
+			x = atlasPadding
+			y += rowHeight + atlasPadding
+			rowHeight = 0
+		}
+
+		rects[name] = image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x, y).Add(size)}
+		x += size.X + atlasPadding
+		if x > width {
+			width = x
+		}
+		if size.Y > rowHeight {
+			rowHeight = size.Y
+		}
+	}
+	height := y + rowHeight + atlasPadding
+
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	for name, rect := range rects {
+		src := images[name]
+		draw.Draw(sheet, rect, src, src.Bounds().Min, draw.Src)
+	}
+
+	sa.image = ebiten.NewImageFromImage(sheet)
+	for name, rect := range rects {
+		sa.sprites[name] = sa.image.SubImage(rect).(*ebiten.Image)
+	}
+}
+
+// the sprite with this name, nil if there isn't one
+func (sa *spriteAtlas) Sprite(name string) *ebiten.Image {
+	return sa.sprites[name]
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const bindingsFileName = "bindings.json"
+const bindingsFileVersion = 1
+
+// the keyboard can be split into several controllers so people can share it
+const defaultKeyboardControllers = 2
+const maxKeyboardControllers = 4
+
+// how far a stick has to move before it counts as pressed
+const defaultStickDeadzone = 0.5
+const minStickDeadzone = 0.1
+const maxStickDeadzone = 0.9
+
+type bindingKind int
+
+const (
+	keyBinding bindingKind = iota
+	buttonBinding
+	axisBinding
+	standardButtonBinding
+	standardAxisBinding
+)
+
+// standard layout names in ebiten's order, used in the bindings file
+var standardButtonNames = [...]string{"RightBottom", "RightRight", "RightLeft", "RightTop",
+	"FrontTopLeft", "FrontTopRight", "FrontBottomLeft", "FrontBottomRight", "CenterLeft", "CenterRight",
+	"LeftStick", "RightStick", "LeftTop", "LeftBottom", "LeftLeft", "LeftRight", "CenterCenter"}
+var standardAxisNames = [...]string{"LeftStickHorizontal", "LeftStickVertical",
+	"RightStickHorizontal", "RightStickVertical"}
+
+// one key, button or axis direction
+type inputBinding struct {
+	kind      bindingKind
+	code      int
+	direction int // axis bindings only, 1 or -1
+}
+
+// every binding for each action on one device
+type deviceBindings map[match.InputAction][]inputBinding
+
+// bindings are written as key:ArrowLeft, button:13, axis:0-, std:RightBottom
+// or stdaxis:LeftStickVertical+ in the bindings file
+func (b inputBinding) String() string {
+	sign := "+"
+	if b.direction < 0 {
+		sign = "-"
+	}
+
+	switch b.kind {
+	case keyBinding:
+		return "key:" + ebiten.Key(b.code).String()
+	case buttonBinding:
+		return "button:" + strconv.Itoa(b.code)
+	case standardButtonBinding:
+		return "std:" + standardButtonNames[b.code]
+	case standardAxisBinding:
+		return "stdaxis:" + standardAxisNames[b.code] + sign
+	}
+
+	return "axis:" + strconv.Itoa(b.code) + sign
+}
+
+// split the + or - off the end of an axis binding
+func parseAxisDirection(s string, value string) (string, int, error) {
+	if len(value) < 2 {
+		return "", 0, fmt.Errorf("binding %q has a bad axis", s)
+	}
+
+	switch value[len(value)-1] {
+	case '+':
+		return value[:len(value)-1], 1, nil
+	case '-':
+		return value[:len(value)-1], -1, nil
+	}
+	return "", 0, fmt.Errorf("binding %q needs + or - on the axis", s)
+}
+
+func nameIndex(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseBinding(s string) (inputBinding, error) {
+	kind, value, found := strings.Cut(s, ":")
+	if !found {
+		return inputBinding{}, fmt.Errorf("binding %q has no kind", s)
+	}
+
+	switch kind {
+	case "key":
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(value)); err != nil {
+			return inputBinding{}, fmt.Errorf("binding %q: %w", s, err)
+		}
+		return inputBinding{kind: keyBinding, code: int(key)}, nil
+	case "button":
+		button, err := strconv.Atoi(value)
+		if err != nil || button < 0 {
+			return inputBinding{}, fmt.Errorf("binding %q has a bad button", s)
+		}
+		return inputBinding{kind: buttonBinding, code: button}, nil
+	case "axis":
+		axisName, direction, err := parseAxisDirection(s, value)
+		if err != nil {
+			return inputBinding{}, err
+		}
+		axis, err := strconv.Atoi(axisName)
+		if err != nil || axis < 0 {
+			return inputBinding{}, fmt.Errorf("binding %q has a bad axis", s)
+		}
+		return inputBinding{kind: axisBinding, code: axis, direction: direction}, nil
+	case "std":
+		button := nameIndex(standardButtonNames[:], value)
+		if button < 0 {
+			return inputBinding{}, fmt.Errorf("binding %q has an unknown button", s)
+		}
+		return inputBinding{kind: standardButtonBinding, code: button}, nil
+	case "stdaxis":
+		axisName, direction, err := parseAxisDirection(s, value)
+		if err != nil {
+			return inputBinding{}, err
+		}
+		axis := nameIndex(standardAxisNames[:], axisName)
+		if axis < 0 {
+			return inputBinding{}, fmt.Errorf("binding %q has an unknown axis", s)
+		}
+		return inputBinding{kind: standardAxisBinding, code: axis, direction: direction}, nil
+	}
+
+	return inputBinding{}, fmt.Errorf("binding %q has unknown kind %s", s, kind)
+}
+
+// a device's bindings from lists of binding strings by action name
+func parseDeviceBindings(actions map[string][]string) (deviceBindings, error) {
+	bindings := make(deviceBindings)
+	for name, list := range actions {
+		action, err := match.ActionFromName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		bindings[action] = make([]inputBinding, 0, len(list))
+		for _, s := range list {
+			b, err := parseBinding(s)
+			if err != nil {
+				return nil, err
+			}
+			bindings[action] = append(bindings[action], b)
+		}
+	}
+	return bindings, nil
+}
+
+// check whether a binding is held, gamepadID is ignored for keys
+// sticks count as pressed once they're pushed past the deadzone
+func isBindingPressed(gamepadID ebiten.GamepadID, b inputBinding, deadzone float64) bool {
+	switch b.kind {
+	case keyBinding:
+		return ebiten.IsKeyPressed(ebiten.Key(b.code))
+	case buttonBinding:
+		return ebiten.IsGamepadButtonPressed(gamepadID, ebiten.GamepadButton(b.code))
+	case axisBinding:
+		if b.code >= ebiten.GamepadAxisCount(gamepadID) {
+			return false
+		}
+		return ebiten.GamepadAxisValue(gamepadID, b.code)*float64(b.direction) > deadzone
+	case standardButtonBinding:
+		return ebiten.IsStandardGamepadButtonPressed(gamepadID, ebiten.StandardGamepadButton(b.code))
+	case standardAxisBinding:
+		return ebiten.StandardGamepadAxisValue(gamepadID, ebiten.StandardGamepadAxis(b.code))*float64(b.direction) > deadzone
+	}
+	return false
+}
+
+// which actions have at least one binding held
+func (bindings deviceBindings) pressedActions(gamepadID ebiten.GamepadID, deadzone float64) [match.InputActionCount]bool {
+	var pressed [match.InputActionCount]bool
+	for action := match.InputAction(0); action < match.InputActionCount; action++ {
+		for _, b := range bindings[action] {
+			if isBindingPressed(gamepadID, b, deadzone) {
+				pressed[action] = true
+				break
+			}
+		}
+	}
+	return pressed
+}
+
+func (bindings deviceBindings) clone() deviceBindings {
+	copied := make(deviceBindings)
+	for action, list := range bindings {
+		copied[action] = append([]inputBinding{}, list...)
+	}
+	return copied
+}
+
+// name the keyboard controller's bindings are saved under
+func keyboardDeviceName(index int) string {
+	if index == 0 {
+		return "keyboard"
+	}
+	return fmt.Sprintf("keyboard%d", index+1)
+}
+
+// arrows on the right of the keyboard and WASD on the left,
+// any keyboard controllers past those start unbound
+func defaultKeyboardBindings(index int) deviceBindings {
+	key := func(k ebiten.Key) []inputBinding {
+		return []inputBinding{{kind: keyBinding, code: int(k)}}
+	}
+
+	switch index {
+	case 0:
+		return deviceBindings{
+			match.ActionPrimary:   key(ebiten.KeyPeriod),
+			match.ActionSecondary: key(ebiten.KeyComma),
+			match.ActionStart:     key(ebiten.KeyEnter),
+			match.ActionSelect:    key(ebiten.KeyBackspace),
+			match.ActionLeft:      key(ebiten.KeyArrowLeft),
+			match.ActionRight:     key(ebiten.KeyArrowRight),
+			match.ActionDown:      key(ebiten.KeyArrowDown),
+			match.ActionUp:        key(ebiten.KeyArrowUp),
+		}
+	case 1:
+		return deviceBindings{
+			match.ActionPrimary:   key(ebiten.KeyE),
+			match.ActionSecondary: key(ebiten.KeyQ),
+			match.ActionStart:     key(ebiten.KeyR),
+			match.ActionSelect:    key(ebiten.KeyTab),
+			match.ActionLeft:      key(ebiten.KeyA),
+			match.ActionRight:     key(ebiten.KeyD),
+			match.ActionDown:      key(ebiten.KeyS),
+			match.ActionUp:        key(ebiten.KeyW),
+		}
+	}
+
+	return deviceBindings{}
+}
+
+// pads ebiten knows the layout of, the left stick doubles as the D-pad
+func defaultStandardGamepadBindings() deviceBindings {
+	button := func(buttons ...ebiten.StandardGamepadButton) []inputBinding {
+		list := make([]inputBinding, 0)
+		for _, b := range buttons {
+			list = append(list, inputBinding{kind: standardButtonBinding, code: int(b)})
+		}
+		return list
+	}
+	stick := func(axis ebiten.StandardGamepadAxis, direction int) inputBinding {
+		return inputBinding{kind: standardAxisBinding, code: int(axis), direction: direction}
+	}
+
+	return deviceBindings{
+		match.ActionPrimary:   button(ebiten.StandardGamepadButtonRightBottom),
+		match.ActionSecondary: button(ebiten.StandardGamepadButtonRightRight, ebiten.StandardGamepadButtonRightLeft),
+		match.ActionStart:     button(ebiten.StandardGamepadButtonCenterRight),
+		match.ActionSelect:    button(ebiten.StandardGamepadButtonCenterLeft),
+		match.ActionLeft: append(button(ebiten.StandardGamepadButtonLeftLeft),
+			stick(ebiten.StandardGamepadAxisLeftStickHorizontal, -1)),
+		match.ActionRight: append(button(ebiten.StandardGamepadButtonLeftRight),
+			stick(ebiten.StandardGamepadAxisLeftStickHorizontal, 1)),
+		match.ActionDown: append(button(ebiten.StandardGamepadButtonLeftBottom),
+			stick(ebiten.StandardGamepadAxisLeftStickVertical, 1)),
+		match.ActionUp: append(button(ebiten.StandardGamepadButtonLeftTop),
+			stick(ebiten.StandardGamepadAxisLeftStickVertical, -1)),
+	}
+}
+
+// raw button numbers for an xbox pad on windows, for pads with no known layout
+func defaultGamepadBindings() deviceBindings {
+	button := func(buttons ...int) []inputBinding {
+		list := make([]inputBinding, 0)
+		for _, b := range buttons {
+			list = append(list, inputBinding{kind: buttonBinding, code: b})
+		}
+		return list
+	}
+	axis := func(axis int, direction int) inputBinding {
+		return inputBinding{kind: axisBinding, code: axis, direction: direction}
+	}
+
+	return deviceBindings{
+		match.ActionPrimary:   button(0),
+		match.ActionSecondary: button(1, 2),
+		match.ActionStart:     button(7),
+		match.ActionSelect:    button(6),
+		match.ActionLeft:      append(button(13), axis(0, -1)),
+		match.ActionRight:     append(button(11), axis(0, 1)),
+		match.ActionDown:      append(button(12), axis(1, 1)),
+		match.ActionUp:        append(button(10), axis(1, -1)),
+	}
+}
+
+// on disk layout of the bindings file, bindings by action name by device
+type bindingsFile struct {
+	Version             int                            `json:"version"`
+	KeyboardControllers int                            `json:"keyboardControllers,omitempty"`
+	Devices             map[string]map[string][]string `json:"devices"`
+	Deadzones           map[string]float64             `json:"deadzones,omitempty"`
+}
+
+// bindings for each device, keyed by keyboard or the gamepad's SDL id
+type bindingStore struct {
+	filePath            string
+	keyboardControllers int
+	devices             map[string]deviceBindings
+	deadzones           map[string]float64
+}
+
+func NewBindingStore(filePath string) *bindingStore {
+	store := &bindingStore{filePath: filePath, keyboardControllers: defaultKeyboardControllers}
+	store.devices = make(map[string]deviceBindings)
+	store.deadzones = make(map[string]float64)
+	return store
+}
+
+// load bindings from disk, a missing file means defaults for everything
+func (store *bindingStore) Load() error {
+	data, err := os.ReadFile(store.filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	bf := &bindingsFile{}
+	if err := json.Unmarshal(data, bf); err != nil {
+		return fmt.Errorf("%s: %w", store.filePath, err)
+	}
+
+	if bf.Version > bindingsFileVersion {
+		return fmt.Errorf("bindings file version %d is newer than this game", bf.Version)
+	}
+
+	devices := make(map[string]deviceBindings)
+	for device, actions := range bf.Devices {
+		bindings, err := parseDeviceBindings(actions)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", store.filePath, device, err)
+		}
+		devices[device] = bindings
+	}
+
+	for device, deadzone := range bf.Deadzones {
+		if deadzone < minStickDeadzone || deadzone > maxStickDeadzone {
+			return fmt.Errorf("%s: %s: deadzone %.2f out of range", store.filePath, device, deadzone)
+		}
+	}
+
+	// files without a count get the default
+	keyboardControllers := defaultKeyboardControllers
+	if bf.KeyboardControllers != 0 {
+		keyboardControllers = bf.KeyboardControllers
+	}
+	if keyboardControllers < 1 || keyboardControllers > maxKeyboardControllers {
+		return fmt.Errorf("%s: keyboard controllers must be 1 to %d", store.filePath, maxKeyboardControllers)
+	}
+
+	store.keyboardControllers = keyboardControllers
+	store.devices = devices
+	if bf.Deadzones != nil {
+		store.deadzones = bf.Deadzones
+	}
+	return nil
+}
+
+func (store *bindingStore) Save() error {
+	if store.filePath == "" {
+		return errors.New("no bindings file path")
+	}
+
+	bf := bindingsFile{Version: bindingsFileVersion, Devices: make(map[string]map[string][]string),
+		KeyboardControllers: store.keyboardControllers, Deadzones: store.deadzones}
+	for device, bindings := range store.devices {
+		actions := make(map[string][]string)
+		for action, list := range bindings {
+			names := make([]string, len(list))
+			for i, b := range list {
+				names[i] = b.String()
+			}
+			actions[match.InputActionNames[action]] = names
+		}
+		bf.Devices[device] = actions
+	}
+
+	data, err := json.MarshalIndent(bf, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(store.filePath, data)
+}
+
+// saved bindings for the device, false if it has none and should use defaults
+func (store *bindingStore) ForDevice(device string) (deviceBindings, bool) {
+	bindings, exists := store.devices[device]
+	return bindings, exists
+}
+
+func (store *bindingStore) SetDevice(device string, bindings deviceBindings) {
+	store.devices[device] = bindings
+}
+
+func (store *bindingStore) KeyboardControllers() int {
+	return store.keyboardControllers
+}
+
+func (store *bindingStore) Deadzone(device string) float64 {
+	if deadzone, exists := store.deadzones[device]; exists {
+		return deadzone
+	}
+	return defaultStickDeadzone
+}
+
+func (store *bindingStore) SetDeadzone(device string, deadzone float64) {
+	store.deadzones[device] = deadzone
+}
+
+// short list of an action's bindings for menus
+func describeBindings(list []inputBinding) string {
+	if len(list) == 0 {
+		return "unbound"
+	}
+
+	names := make([]string, len(list))
+	for i, b := range list {
+		names[i] = b.String()
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"strings"
+
+	"example.com/drbreakboard"
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+type EditorMode int
+type EditorAction int
+type EditorMenuItem int
+
+const (
+	EditBoard EditorMode = iota
+	EditBrush
+	EditPills
+	EditMenu
+	editorModeCount
+)
+
+// actions the editor hands back to the game
+const (
+	EditorNoAction EditorAction = iota
+	EditorStartTestPlay
+	EditorExit
+)
+
+const (
+	MenuTestPlay EditorMenuItem = iota
+	MenuSave
+	MenuLoad
+	MenuClear
+	MenuExit
+	editorMenuItemCount
+)
+
+const editorBoardPath = "./editor.board"
+
+var editorModeNames = [...]string{"Board", "Brush", "Pills", "Menu"}
+var editorMenuNames = [...]string{"Test Play", "Save", "Load", "Clear", "Exit"}
+var editorModeHelp = [...]string{
+	"Arrows: move\nA: paint\nB: erase",
+	"Left/Right:\nchange brush\nA: done",
+	"Left/Right: move\nUp/Down: colour\nA: insert\nB: remove",
+	"Up/Down: pick\nA: select",
+}
+
+var editorColors = []drbreakboard.SpaceColor{drbreakboard.Red, drbreakboard.Yellow, drbreakboard.Blue}
+
+// every space the editor can paint
+// viruses first, then each colour's pill halves
+var editorBrushes = buildEditorBrushes()
+
+func buildEditorBrushes() []drbreakboard.Space {
+	brushes := make([]drbreakboard.Space, 0)
+	for _, c := range editorColors {
+		virus, _ := drbreakboard.MakeVirus(c)
+		brushes = append(brushes, virus)
+	}
+
+	for _, c := range editorColors {
+		brushes = append(brushes,
+			drbreakboard.Space{Content: drbreakboard.Pill, Color: c, Linkage: drbreakboard.Unlinked},
+			drbreakboard.Space{Content: drbreakboard.Pill, Color: c, Linkage: drbreakboard.Right},
+			drbreakboard.Space{Content: drbreakboard.Pill, Color: c, Linkage: drbreakboard.Left},
+			drbreakboard.Space{Content: drbreakboard.Pill, Color: c, Linkage: drbreakboard.Up},
+			drbreakboard.Space{Content: drbreakboard.Pill, Color: c, Linkage: drbreakboard.Down})
+	}
+
+	return brushes
+}
+
+type boardEditor struct {
+	board      *match.BoardFile
+	filePath   string
+	mode       EditorMode
+	cursorY    int
+	cursorX    int
+	brushIndex int
+	pillCursor int // index into the pill sequence, len(pills) is the append slot
+	menuIndex  EditorMenuItem
+	message    string // last result shown to the player, e.g. save errors
+	viz        *playfieldViz
+	fontMap    fontMap
+}
+
+func NewBoardEditor(atlas *spriteAtlas, gameFontMap fontMap, filePath string) *boardEditor {
+	editor := &boardEditor{filePath: filePath, fontMap: gameFontMap}
+
+	editor.viz = NewPlayfieldViz(atlas, gameFontMap)
+	editor.viz.SetPixelSizeAndOffset(160, 480, 240, 0)
+
+	editor.load()
+
+	return editor
+}
+
+// load the board file, starting from an empty board if it's missing or bad
+func (editor *boardEditor) load() {
+	bf, err := match.LoadBoardFile(editor.filePath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		editor.clear()
+		editor.message = "New board"
+	case err != nil:
+		editor.clear()
+		editor.message = err.Error()
+	case bf.Playfield.GetWidth() != match.BoardWidth || bf.Playfield.GetHeight() != match.BoardHeight:
+		editor.clear()
+		editor.message = fmt.Sprintf("Board must be %dx%d", match.BoardWidth, match.BoardHeight)
+	default:
+		editor.board = bf
+		editor.pillCursor = 0
+		editor.message = "Loaded " + editor.filePath
+	}
+
+	editor.viz.UpdateBoard(editor.board.Playfield, [2]drbreakboard.Space{}, [2]int{})
+}
+
+func (editor *boardEditor) clear() {
+	editor.board = &match.BoardFile{
+		Playfield: drbreakboard.NewPlayField(match.BoardWidth, match.BoardHeight),
+		Pills:     make([][2]drbreakboard.SpaceColor, 0),
+	}
+	editor.pillCursor = 0
+}
+
+// round trip the board through the file format to catch broken linkage
+func (editor *boardEditor) validate() error {
+	var buf bytes.Buffer
+	if err := match.WriteBoard(&buf, editor.board); err != nil {
+		return err
+	}
+
+	_, err := match.ParseBoard(&buf)
+	return err
+}
+
+func (editor *boardEditor) save() {
+	if err := editor.validate(); err != nil {
+		editor.message = err.Error()
+		return
+	}
+
+	if err := match.SaveBoardFile(editor.filePath, editor.board); err != nil {
+		editor.message = err.Error()
+		return
+	}
+
+	editor.message = "Saved " + editor.filePath
+}
+
+// handle one controller's events for the frame
+func (editor *boardEditor) Update(events []match.GamepadEvent) EditorAction {
+	action := EditorNoAction
+
+	for _, event := range events {
+		switch {
+		case event == match.StartJustPressed:
+			action = editor.requestTestPlay()
+		case event == match.SelectJustPressed:
+			editor.mode = (editor.mode + 1) % editorModeCount
+		case editor.mode == EditBoard:
+			editor.updateBoardMode(event)
+		case editor.mode == EditBrush:
+			editor.updateBrushMode(event)
+		case editor.mode == EditPills:
+			editor.updatePillsMode(event)
+		case editor.mode == EditMenu:
+			action = editor.updateMenuMode(event)
+		}
+
+		if action != EditorNoAction {
+			return action
+		}
+	}
+
+	editor.viz.UpdateBoard(editor.board.Playfield, [2]drbreakboard.Space{}, [2]int{})
+
+	return EditorNoAction
+}
+
+func (editor *boardEditor) requestTestPlay() EditorAction {
+	if err := editor.validate(); err != nil {
+		editor.message = err.Error()
+		return EditorNoAction
+	}
+
+	return EditorStartTestPlay
+}
+
+func (editor *boardEditor) updateBoardMode(event match.GamepadEvent) {
+	switch event {
+	case match.UpJustPressed:
+		if editor.cursorY > 0 {
+			editor.cursorY--
+		}
+	case match.DownJustPressed:
+		if editor.cursorY < match.BoardHeight-1 {
+			editor.cursorY++
+		}
+	case match.LeftJustPressed:
+		if editor.cursorX > 0 {
+			editor.cursorX--
+		}
+	case match.RightJustPressed:
+		if editor.cursorX < match.BoardWidth-1 {
+			editor.cursorX++
+		}
+	case match.PrimaryJustPressed:
+		editor.putSpace(editorBrushes[editor.brushIndex])
+	case match.SecondaryJustPressed:
+		editor.putSpace(drbreakboard.Space{Content: drbreakboard.Empty})
+	}
+}
+
+func (editor *boardEditor) updateBrushMode(event match.GamepadEvent) {
+	switch event {
+	case match.LeftJustPressed:
+		editor.brushIndex--
+		if editor.brushIndex < 0 {
+			editor.brushIndex = len(editorBrushes) - 1
+		}
+	case match.RightJustPressed:
+		editor.brushIndex = (editor.brushIndex + 1) % len(editorBrushes)
+	case match.PrimaryJustPressed:
+		editor.mode = EditBoard
+	}
+}
+
+func (editor *boardEditor) updatePillsMode(event match.GamepadEvent) {
+	pills := editor.board.Pills
+
+	switch event {
+	case match.LeftJustPressed:
+		if editor.pillCursor > 0 {
+			editor.pillCursor--
+		}
+	case match.RightJustPressed:
+		if editor.pillCursor < len(pills) {
+			editor.pillCursor++
+		}
+	case match.UpJustPressed, match.DownJustPressed:
+		if editor.pillCursor >= len(pills) {
+			return
+		}
+
+		// step through the 9 colour combinations
+		combo := colorIndex(pills[editor.pillCursor][0])*len(editorColors) + colorIndex(pills[editor.pillCursor][1])
+		comboCount := len(editorColors) * len(editorColors)
+		if event == match.UpJustPressed {
+			combo = (combo + 1) % comboCount
+		} else {
+			combo = (combo + comboCount - 1) % comboCount
+		}
+		pills[editor.pillCursor] = [2]drbreakboard.SpaceColor{
+			editorColors[combo/len(editorColors)], editorColors[combo%len(editorColors)]}
+	case match.PrimaryJustPressed:
+		// insert a new pill at the cursor
+		pills = append(pills, [2]drbreakboard.SpaceColor{})
+		copy(pills[editor.pillCursor+1:], pills[editor.pillCursor:])
+		pills[editor.pillCursor] = [2]drbreakboard.SpaceColor{editorColors[0], editorColors[0]}
+		editor.board.Pills = pills
+	case match.SecondaryJustPressed:
+		if editor.pillCursor < len(pills) {
+			editor.board.Pills = append(pills[:editor.pillCursor], pills[editor.pillCursor+1:]...)
+		}
+	}
+}
+
+func (editor *boardEditor) updateMenuMode(event match.GamepadEvent) EditorAction {
+	switch event {
+	case match.UpJustPressed:
+		editor.menuIndex = (editor.menuIndex + editorMenuItemCount - 1) % editorMenuItemCount
+	case match.DownJustPressed:
+		editor.menuIndex = (editor.menuIndex + 1) % editorMenuItemCount
+	case match.PrimaryJustPressed:
+		switch editor.menuIndex {
+		case MenuTestPlay:
+			return editor.requestTestPlay()
+		case MenuSave:
+			editor.save()
+		case MenuLoad:
+			editor.load()
+		case MenuClear:
+			editor.clear()
+			editor.message = "Cleared"
+		case MenuExit:
+			return EditorExit
+		}
+	}
+
+	return EditorNoAction
+}
+
+// put a space under the cursor
+// anything linked to the space being replaced is unlinked so the board stays valid
+func (editor *boardEditor) putSpace(space drbreakboard.Space) {
+	playfield := editor.board.Playfield
+
+	old, err := playfield.GetSpaceAtCoordinate(editor.cursorY, editor.cursorX)
+	if err != nil {
+		return
+	}
+
+	if old.Content == drbreakboard.Pill && old.Linkage != drbreakboard.Unlinked {
+		linkedY, linkedX, err := drbreakboard.GetLinkedCoordinate(editor.cursorY, editor.cursorX, old.Linkage)
+		if err == nil {
+			partner, err := playfield.GetSpaceAtCoordinate(linkedY, linkedX)
+			if err == nil && partner.Content == drbreakboard.Pill {
+				partner.Linkage = drbreakboard.Unlinked
+				playfield.ForcePutSingleSpaceIntoBoard(linkedY, linkedX, partner)
+			}
+		}
+	}
+
+	playfield.ForcePutSingleSpaceIntoBoard(editor.cursorY, editor.cursorX, space)
+}
+
+func (editor *boardEditor) Draw(screen *ebiten.Image) {
+	textColor := color.RGBA{128, 128, 128, 255}
+	face := editor.fontMap["base"]
+
+	editor.viz.DrawBoardToImage(screen)
+	editor.viz.DrawCursorToImage(screen, editor.cursorY, editor.cursorX)
+
+	// help for the current mode on the left
+	text.Draw(screen, editorModeHelp[editor.mode]+"\nSelect: mode\nStart: test", face, 10, 30, textColor)
+
+	// editor state on the right
+	const infoX = 410
+	text.Draw(screen, "Mode: "+editorModeNames[editor.mode], face, infoX, 30, textColor)
+
+	text.Draw(screen, "Brush:", face, infoX, 70, textColor)
+	editor.viz.DrawSpaceToImage(screen, editorBrushes[editor.brushIndex], infoX+80, 50, 24)
+
+	text.Draw(screen, fmt.Sprintf("Pills: %d", len(editor.board.Pills)), face, infoX, 110, textColor)
+	text.Draw(screen, editor.pillWindow(), face, infoX, 140, textColor)
+
+	if editor.mode == EditMenu {
+		for i, name := range editorMenuNames {
+			marker := "  "
+			if EditorMenuItem(i) == editor.menuIndex {
+				marker = "> "
+			}
+			text.Draw(screen, marker+name, face, infoX, 190+i*30, textColor)
+		}
+	}
+
+	text.Draw(screen, editor.message, face, 10, 460, textColor)
+}
+
+// a few pills around the pill cursor, with the cursor pill in brackets
+func (editor *boardEditor) pillWindow() string {
+	const shown = 2 // pills shown on each side of the cursor
+
+	var sb strings.Builder
+	pills := editor.board.Pills
+	for i := editor.pillCursor - shown; i <= editor.pillCursor+shown; i++ {
+		if i < 0 || i > len(pills) {
+			continue
+		}
+
+		label := "+"
+		if i < len(pills) {
+			first, _ := match.CharFromColor(pills[i][0])
+			second, _ := match.CharFromColor(pills[i][1])
+			label = string([]byte{first, second})
+		}
+
+		if i == editor.pillCursor {
+			label = "[" + label + "]"
+		}
+		sb.WriteString(label + " ")
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+func colorIndex(c drbreakboard.SpaceColor) int {
+	for i, editorColor := range editorColors {
+		if editorColor == c {
+			return i
+		}
+	}
+	return 0
+}
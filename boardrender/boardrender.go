@@ -0,0 +1,192 @@
+// Package boardrender draws boards straight into an image with image/draw. Nothing here
+// touches ebiten, so the output doesn't depend on a gpu and is the same on every machine.
+package boardrender
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+
+	"example.com/drbreakboard"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// a mark drawn over one space, e.g. to point at the space a bug report is about
+type Annotation struct {
+	Y     int
+	X     int
+	Label string // drawn in the space, can be empty
+}
+
+// everything that goes into one rendered board
+type Board struct {
+	Playfield          *drbreakboard.PlayField
+	ActivePill         [2]drbreakboard.Space // Empty content for no active pill
+	ActivePillLocation [2]int
+	Annotations        []Annotation
+	Caption            string // a line under the board, e.g. the replay tick
+}
+
+var annotationColor = color.RGBA{255, 0, 255, 255}
+var renderBackground = color.RGBA{0, 0, 0, 255}
+var renderTextColor = color.RGBA{255, 255, 255, 255}
+
+var spriteColorNames = map[drbreakboard.SpaceColor]string{
+	drbreakboard.Red:    "red",
+	drbreakboard.Blue:   "blue",
+	drbreakboard.Yellow: "yellow",
+}
+
+// the sprite a space is drawn with, empty for an empty space
+func spriteNameForSpace(space drbreakboard.Space) string {
+	colorName := spriteColorNames[space.Color]
+	switch {
+	case space.Content == drbreakboard.Virus:
+		return colorName + "Virus"
+	case space.Content != drbreakboard.Pill:
+		return ""
+	case space.Linkage == drbreakboard.Unlinked:
+		return colorName + "Single"
+	default:
+		return colorName + "Linked"
+	}
+}
+
+// draw the board one sprite sized cell per space, with the border and annotations around it
+// face is used for labels and the caption
+func Render(images map[string]image.Image, br Board, face font.Face) (*image.RGBA, error) {
+	cellSprite, hasCell := images["redVirus"]
+	if !hasCell {
+		return nil, errors.New("no sprites to render with")
+	}
+	cell := cellSprite.Bounds().Dx()
+	margin := cell / 2
+	lineWidth := cell / 8
+	if lineWidth < 1 {
+		lineWidth = 1
+	}
+
+	boardRect := image.Rect(margin, margin,
+		margin+br.Playfield.GetWidth()*cell, margin+br.Playfield.GetHeight()*cell)
+	height := boardRect.Max.Y + margin
+	if br.Caption != "" {
+		height += cell
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, boardRect.Max.X+margin, height))
+	draw.Draw(out, out.Bounds(), image.NewUniform(renderBackground), image.Point{}, draw.Src)
+
+	borderColor := color.Color(color.RGBA{0, 255, 0, 255})
+	if border, hasBorder := images["border"]; hasBorder {
+		borderColor = border.At(border.Bounds().Min.X, border.Bounds().Min.Y)
+	}
+	drawOutline(out, boardRect.Inset(-lineWidth), lineWidth, borderColor)
+
+	cellRect := func(y int, x int) image.Rectangle {
+		topLeft := boardRect.Min.Add(image.Pt(x*cell, y*cell))
+		return image.Rectangle{Min: topLeft, Max: topLeft.Add(image.Pt(cell, cell))}
+	}
+
+	for y := 0; y < br.Playfield.GetHeight(); y++ {
+		for x := 0; x < br.Playfield.GetWidth(); x++ {
+			space, _ := br.Playfield.GetSpaceAtCoordinate(y, x)
+			drawSpaceSprite(out, images, space, cellRect(y, x))
+		}
+	}
+
+	if br.ActivePill[0].Content != drbreakboard.Empty {
+		y, x := br.ActivePillLocation[0], br.ActivePillLocation[1]
+		drawSpaceSprite(out, images, br.ActivePill[0], cellRect(y, x))
+		linkedY, linkedX, err := drbreakboard.GetLinkedCoordinate(y, x, br.ActivePill[0].Linkage)
+		if err == nil {
+			drawSpaceSprite(out, images, br.ActivePill[1], cellRect(linkedY, linkedX))
+		}
+	}
+
+	for _, annotation := range br.Annotations {
+		rect := cellRect(annotation.Y, annotation.X)
+		drawOutline(out, rect, lineWidth, annotationColor)
+		if annotation.Label != "" {
+			drawRenderText(out, face, annotation.Label, rect.Min.X+lineWidth+1, rect.Max.Y-lineWidth-1)
+		}
+	}
+
+	if br.Caption != "" {
+		drawRenderText(out, face, br.Caption, boardRect.Min.X, boardRect.Max.Y+margin+cell/2)
+	}
+
+	return out, nil
+}
+
+// draw a space's sprite scaled to rect, linked halves turned the same way the game turns them
+func drawSpaceSprite(dst *image.RGBA, images map[string]image.Image, space drbreakboard.Space, rect image.Rectangle) {
+	sprite, exists := images[spriteNameForSpace(space)]
+	if !exists {
+		return
+	}
+
+	// quarter turns clockwise, matching the rotations in drawBoardSpace
+	turns := 0
+	if space.Content == drbreakboard.Pill {
+		switch space.Linkage {
+		case drbreakboard.Left:
+			turns = 1
+		case drbreakboard.Up:
+			turns = 2
+		case drbreakboard.Right:
+			turns = 3
+		}
+	}
+
+	bounds := sprite.Bounds()
+	cellImage := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			// find the sprite pixel that lands here by turning back the other way
+			u, v := x, y
+			for i := 0; i < turns; i++ {
+				u, v = v, rect.Dx()-1-u
+			}
+			srcX := bounds.Min.X + u*bounds.Dx()/rect.Dx()
+			srcY := bounds.Min.Y + v*bounds.Dy()/rect.Dy()
+			cellImage.Set(x, y, sprite.At(srcX, srcY))
+		}
+	}
+
+	draw.Draw(dst, rect, cellImage, image.Point{}, draw.Over)
+}
+
+// a frame of width pixels just inside rect
+func drawOutline(dst *image.RGBA, rect image.Rectangle, width int, c color.Color) {
+	src := image.NewUniform(c)
+	draw.Draw(dst, image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+width), src, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(rect.Min.X, rect.Max.Y-width, rect.Max.X, rect.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+width, rect.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(rect.Max.X-width, rect.Min.Y, rect.Max.X, rect.Max.Y), src, image.Point{}, draw.Src)
+}
+
+// text with its baseline at y
+func drawRenderText(dst *image.RGBA, face font.Face, s string, x int, y int) {
+	if face == nil {
+		return
+	}
+	drawer := font.Drawer{Dst: dst, Src: image.NewUniform(renderTextColor), Face: face, Dot: fixed.P(x, y)}
+	drawer.DrawString(s)
+}
+
+func WritePNG(filePath string, img image.Image) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
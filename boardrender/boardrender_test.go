@@ -0,0 +1,117 @@
+package boardrender
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/drbreakboard"
+	"example.com/drbreaktime/assets"
+	"example.com/drbreaktime/match"
+	"golang.org/x/image/font/basicfont"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden pngs in testdata/golden")
+
+// viruses, single halves and pills linked every way
+const testBoardText = `................
+................
+....R*..........
+....Yv......B>Y<
+....B^..........
+R*..B*Y*Ro......
+`
+
+func TestRenderBoardFile(t *testing.T) {
+	images, err := assets.LoadBuiltinImages("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := match.ParseBoard(strings.NewReader(testBoardText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the active pill in board file cells so its halves link the same way a board's do
+	pill, _, err := match.SpaceFromCell("R>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, _, err := match.SpaceFromCell("Y<")
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := Board{
+		Playfield:          bf.Playfield,
+		ActivePill:         [2]drbreakboard.Space{pill, linked},
+		ActivePillLocation: [2]int{0, 2},
+		Annotations:        []Annotation{{Y: 5, X: 1, Label: "x"}},
+		Caption:            "tick 0",
+	}
+
+	img, err := Render(images, br, basicfont.Face7x13)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cell := images["redVirus"].Bounds().Dx()
+	wantWidth := bf.Playfield.GetWidth()*cell + cell
+	if img.Bounds().Dx() != wantWidth {
+		t.Errorf("width is %d, want %d", img.Bounds().Dx(), wantWidth)
+	}
+
+	checkGolden(t, "board.png", img)
+}
+
+func TestRenderNeedsSprites(t *testing.T) {
+	bf, err := match.ParseBoard(strings.NewReader(testBoardText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Render(map[string]image.Image{}, Board{Playfield: bf.Playfield}, nil); err == nil {
+		t.Error("rendered with no sprites")
+	}
+}
+
+// compare img with a png in testdata/golden, or write it with -update
+func checkGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+	goldenPath := filepath.Join("testdata", "golden", name)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WritePNG(goldenPath, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to make it", err)
+	}
+	golden, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("size is %v, golden is %v", img.Bounds(), golden.Bounds())
+	}
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			r1, g1, b1, a1 := golden.At(x, y).RGBA()
+			r2, g2, b2, a2 := img.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				t.Fatalf("pixel %d,%d differs from %s", x, y, goldenPath)
+			}
+		}
+	}
+}
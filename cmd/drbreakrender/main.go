@@ -0,0 +1,293 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"example.com/drbreakboard"
+	"example.com/drbreaktime/assets"
+	"example.com/drbreaktime/boardrender"
+	"example.com/drbreaktime/match"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+// drbreakrender [flags] board.txt
+// drbreakrender [flags] -replay match.json
+//
+// writes boards to png without opening a window, for bug reports and ci
+// this doesn't import ebiten, so it runs on machines with no display
+
+// repeatable -mark flags
+type annotationFlags []boardrender.Annotation
+
+func (af *annotationFlags) String() string {
+	return fmt.Sprint(*af)
+}
+
+// y,x or y,x:label
+func (af *annotationFlags) Set(value string) error {
+	position, label, _ := strings.Cut(value, ":")
+	y, x, err := parseBoardPosition(position)
+	if err != nil {
+		return err
+	}
+	*af = append(*af, boardrender.Annotation{Y: y, X: x, Label: label})
+	return nil
+}
+
+func parseBoardPosition(value string) (int, int, error) {
+	yText, xText, found := strings.Cut(value, ",")
+	if !found {
+		return 0, 0, fmt.Errorf("%q is not y,x", value)
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(yText))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not y,x", value)
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(xText))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not y,x", value)
+	}
+	return y, x, nil
+}
+
+// y,x:AABB with the two halves in board file cells, e.g. 3,4:R>Y<
+func parseActivePill(value string) ([2]drbreakboard.Space, [2]int, error) {
+	pill := [2]drbreakboard.Space{}
+	position, cells, found := strings.Cut(value, ":")
+	if !found || len(cells) != 4 {
+		return pill, [2]int{}, fmt.Errorf("%q is not y,x:AABB", value)
+	}
+
+	y, x, err := parseBoardPosition(position)
+	if err != nil {
+		return pill, [2]int{}, err
+	}
+
+	for i := range pill {
+		pill[i], _, err = match.SpaceFromCell(cells[i*2 : i*2+2])
+		if err != nil {
+			return pill, [2]int{}, fmt.Errorf("pill half %d: %w", i+1, err)
+		}
+		if pill[i].Content != drbreakboard.Pill || pill[i].Linkage == drbreakboard.Unlinked {
+			return pill, [2]int{}, fmt.Errorf("pill half %d is not a linked pill half", i+1)
+		}
+	}
+	return pill, [2]int{y, x}, nil
+}
+
+// frame ticks from a list like 0,60,120
+func parseFrameTicks(value string) ([]int, error) {
+	ticks := make([]int, 0)
+	for _, field := range strings.Split(value, ",") {
+		tick, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || tick < 0 {
+			return nil, fmt.Errorf("bad frame tick %q", field)
+		}
+		ticks = append(ticks, tick)
+	}
+	return ticks, nil
+}
+
+func checkAnnotations(annotations []boardrender.Annotation, playfield *drbreakboard.PlayField) error {
+	for _, annotation := range annotations {
+		if annotation.Y < 0 || annotation.Y >= playfield.GetHeight() ||
+			annotation.X < 0 || annotation.X >= playfield.GetWidth() {
+			return fmt.Errorf("mark %d,%d is off the board", annotation.Y, annotation.X)
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("drbreakrender", flag.ContinueOnError)
+	out := flags.String("out", "", "png to write for a board, or directory for replay frames")
+	assetDir := flags.String("assets", "", "directory of images to use instead of the built-in ones")
+	pillValue := flags.String("pill", "", "active pill to draw over a board, y,x:AABB in board file cells")
+	replayPath := flags.String("replay", "", "replay file to render frames from instead of a board")
+	framesValue := flags.String("frames", "", "replay ticks to render, e.g. 0,60,120")
+	every := flags.Int("every", 60, "with no -frames, render a replay frame this many ticks apart")
+	player := flags.Int("player", 1, "whose board to render from a replay")
+	var annotations annotationFlags
+	flags.Var(&annotations, "mark", "outline a space, y,x or y,x:label, can be repeated")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: drbreakrender [flags] board.txt")
+		fmt.Fprintln(flags.Output(), "       drbreakrender [flags] -replay match.json")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	images, err := assets.LoadBuiltinImages(*assetDir)
+	if err != nil {
+		return err
+	}
+	face, err := newTextFace()
+	if err != nil {
+		return err
+	}
+
+	if *replayPath != "" {
+		if flags.NArg() != 0 || *pillValue != "" {
+			return errors.New("a replay takes no board file or -pill")
+		}
+		outDir := *out
+		if outDir == "" {
+			outDir = "frames"
+		}
+		rr := replayRender{path: *replayPath, outDir: outDir, frames: *framesValue, every: *every,
+			playerIndex: *player - 1, annotations: annotations}
+		return renderReplayFrames(images, face, rr)
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("needs one board file")
+	}
+
+	bf, err := match.LoadBoardFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := checkAnnotations(annotations, bf.Playfield); err != nil {
+		return err
+	}
+
+	br := boardrender.Board{Playfield: bf.Playfield, Annotations: annotations}
+	if *pillValue != "" {
+		br.ActivePill, br.ActivePillLocation, err = parseActivePill(*pillValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	outPath := *out
+	if outPath == "" {
+		outPath = strings.TrimSuffix(filepath.Base(flags.Arg(0)), filepath.Ext(flags.Arg(0))) + ".png"
+	}
+
+	img, err := boardrender.Render(images, br, face)
+	if err != nil {
+		return err
+	}
+	return boardrender.WritePNG(outPath, img)
+}
+
+// what to render from a replay
+type replayRender struct {
+	path        string
+	outDir      string
+	frames      string // ticks to render, every is used when it's empty
+	every       int
+	playerIndex int
+	annotations []boardrender.Annotation
+}
+
+// play the replay and write a png of one player's board at each frame tick, stopping early if the match ends
+// prints everyone's stats once the match is over
+func renderReplayFrames(images map[string]image.Image, face font.Face, rr replayRender) error {
+	rf, err := match.LoadReplayFile(rr.path)
+	if err != nil {
+		return err
+	}
+	if rr.playerIndex < 0 || rr.playerIndex >= rf.PlayerCount() {
+		return fmt.Errorf("the replay has %d players", rf.PlayerCount())
+	}
+
+	var ticks []int
+	if rr.frames != "" {
+		ticks, err = parseFrameTicks(rr.frames)
+		if err != nil {
+			return err
+		}
+	} else {
+		if rr.every < 1 {
+			return errors.New("-every must be at least 1")
+		}
+		// a second past the last input so the final pill lands
+		for tick := 0; tick <= rf.LastInputTick()+match.TicksPerSecond; tick += rr.every {
+			ticks = append(ticks, tick)
+		}
+	}
+
+	rm, err := match.NewReplayMatch(rf)
+	if err != nil {
+		return err
+	}
+	md := rm.Driver()
+	if err := checkAnnotations(rr.annotations, md.GetPlayfield(rr.playerIndex)); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(rr.outDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, tick := range ticks {
+		if tick < rm.Tick() {
+			return fmt.Errorf("frame ticks need to go up, %d is after %d", tick, rm.Tick())
+		}
+		rm.StepTo(tick)
+
+		br := boardrender.Board{
+			Playfield:          md.GetPlayfield(rr.playerIndex),
+			ActivePill:         md.GetActivePill(rr.playerIndex),
+			ActivePillLocation: md.GetActivePillLocation(rr.playerIndex),
+			Annotations:        rr.annotations,
+			Caption:            fmt.Sprintf("tick %d", rm.Tick()),
+		}
+		if rm.Ended() {
+			br.Caption += " (match over)"
+		}
+
+		img, err := boardrender.Render(images, br, face)
+		if err != nil {
+			return err
+		}
+		if err := boardrender.WritePNG(filepath.Join(rr.outDir, fmt.Sprintf("frame-%06d.png", rm.Tick())), img); err != nil {
+			return err
+		}
+
+		if rm.Ended() {
+			break
+		}
+	}
+
+	// a line per player of how the replayed match went
+	if rm.Ended() {
+		for i, player := range rf.Players {
+			if player.Stats == nil {
+				continue
+			}
+			fmt.Printf("player %d: %d pills, %d viruses cleared, longest chain %d, %d garbage sent, %d received\n",
+				i+1, player.Stats.PillsPlaced, player.Stats.VirusesCleared, player.Stats.LongestChain,
+				match.SumGarbage(player.Stats.GarbageSent), match.SumGarbage(player.Stats.GarbageReceived))
+		}
+	}
+	return nil
+}
+
+// the game's font at its small size, for labels and captions
+func newTextFace() (font.Face, error) {
+	fontData, err := opentype.Parse(assets.BaseTextTTF)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(fontData, &opentype.FaceOptions{Size: 14, DPI: 72, Hinting: font.HintingVertical})
+}
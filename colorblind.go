@@ -0,0 +1,244 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"example.com/drbreakboard"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// how a player's viruses and pills are drawn, from their options
+type spriteStyle struct {
+	patterns     bool // a shape per color on top of the sprite
+	highContrast bool // recolor to a palette that stays apart for colour-blind players
+}
+
+// suffixes for the generated versions of the colored sprites
+const patternSuffix = "Pattern"
+const contrastSuffix = "Contrast"
+
+// the sprite to draw for name in this style
+func (style spriteStyle) spriteName(name string) string {
+	if style.highContrast {
+		name += contrastSuffix
+	}
+	if style.patterns {
+		name += patternSuffix
+	}
+	return name
+}
+
+// colored sprites and the color they show
+var colorSpriteNames = map[string]drbreakboard.SpaceColor{
+	"redVirus": drbreakboard.Red, "blueVirus": drbreakboard.Blue, "yellowVirus": drbreakboard.Yellow,
+	"redSingle": drbreakboard.Red, "blueSingle": drbreakboard.Blue, "yellowSingle": drbreakboard.Yellow,
+	"redLinked": drbreakboard.Red, "blueLinked": drbreakboard.Blue, "yellowLinked": drbreakboard.Yellow,
+}
+
+// picked so the colors differ in brightness too, they stay apart in any kind of colour blindness
+var highContrastPalette = map[drbreakboard.SpaceColor]color.NRGBA{
+	drbreakboard.Red:    {230, 97, 0, 255},
+	drbreakboard.Blue:   {0, 64, 160, 255},
+	drbreakboard.Yellow: {255, 235, 80, 255},
+}
+
+// marks go dark on light colors and light on dark ones
+var patternInk = map[drbreakboard.SpaceColor]color.NRGBA{
+	drbreakboard.Red:    {0, 0, 0, 200},
+	drbreakboard.Blue:   {255, 255, 255, 220},
+	drbreakboard.Yellow: {0, 0, 0, 200},
+}
+
+// images plus the pattern and high contrast versions of every colored sprite
+// the result is what the atlas gets built from
+func withColorVariants(images imageMap) imageMap {
+	variants := imageMap{}
+	for name, img := range images {
+		variants[name] = img
+
+		spaceColor, colored := colorSpriteNames[name]
+		if !colored {
+			continue
+		}
+
+		contrast := recolorImage(img, highContrastPalette[spaceColor])
+		variants[name+patternSuffix] = patternImage(img, spaceColor)
+		variants[name+contrastSuffix] = contrast
+		variants[name+contrastSuffix+patternSuffix] = patternImage(contrast, spaceColor)
+	}
+	return variants
+}
+
+// the sprite's shading in a new color, brightest pixels get the full color
+func recolorImage(img image.Image, target color.NRGBA) image.Image {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			shade := c.R
+			if c.G > shade {
+				shade = c.G
+			}
+			if c.B > shade {
+				shade = c.B
+			}
+
+			out.SetNRGBA(x-bounds.Min.X, y-bounds.Min.Y, color.NRGBA{
+				R: uint8(int(target.R) * int(shade) / 255),
+				G: uint8(int(target.G) * int(shade) / 255),
+				B: uint8(int(target.B) * int(shade) / 255),
+				A: c.A,
+			})
+		}
+	}
+	return out
+}
+
+// the sprite with its color's shape in the middle
+// the shapes look the same turned a quarter, so linked pills can rotate them
+func patternImage(img image.Image, spaceColor drbreakboard.SpaceColor) image.Image {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	ink := patternInk[spaceColor]
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+
+			// -1 to 1 across the sprite, sampled at pixel centres
+			u := 2*(float64(x-bounds.Min.X)+0.5)/float64(bounds.Dx()) - 1
+			v := 2*(float64(y-bounds.Min.Y)+0.5)/float64(bounds.Dy()) - 1
+			if c.A > 0 && inPatternShape(spaceColor, u, v) {
+				c = blendNRGBA(c, ink)
+			}
+			out.SetNRGBA(x-bounds.Min.X, y-bounds.Min.Y, c)
+		}
+	}
+	return out
+}
+
+// red gets a dot, blue a square ring, yellow a plus
+func inPatternShape(spaceColor drbreakboard.SpaceColor, u float64, v float64) bool {
+	absU, absV := math.Abs(u), math.Abs(v)
+	switch spaceColor {
+	case drbreakboard.Red:
+		return u*u+v*v <= 0.35*0.35
+	case drbreakboard.Blue:
+		edge := absU
+		if absV > edge {
+			edge = absV
+		}
+		return edge >= 0.25 && edge <= 0.45
+	case drbreakboard.Yellow:
+		return (absU <= 0.12 && absV <= 0.45) || (absV <= 0.12 && absU <= 0.45)
+	default:
+		return false
+	}
+}
+
+// ink over c, keeping c's alpha so the sprite's outline stays the same
+func blendNRGBA(c color.NRGBA, ink color.NRGBA) color.NRGBA {
+	a := int(ink.A)
+	return color.NRGBA{
+		R: uint8((int(ink.R)*a + int(c.R)*(255-a)) / 255),
+		G: uint8((int(ink.G)*a + int(c.G)*(255-a)) / 255),
+		B: uint8((int(ink.B)*a + int(c.B)*(255-a)) / 255),
+		A: c.A,
+	}
+}
+
+// kinds of colour blindness the options screen can preview
+type colorSimulation int
+
+const (
+	NoSimulation colorSimulation = iota
+	Protanopia
+	Deuteranopia
+	Tritanopia
+	colorSimulationCount
+)
+
+var colorSimulationNames = [colorSimulationCount]string{"Off", "Protan", "Deutan", "Tritan"}
+
+// full strength matrices from Machado, Oliveira and Fernandes 2009
+// they're meant for linear rgb, on screen colors they're close enough for a preview
+var colorSimulationMatrices = [colorSimulationCount][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// a color matrix that shows roughly what a player with this colour blindness sees
+func (simulation colorSimulation) ColorM() ebiten.ColorM {
+	colorM := ebiten.ColorM{}
+	if simulation == NoSimulation {
+		return colorM
+	}
+
+	matrix := colorSimulationMatrices[simulation]
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			colorM.SetElement(row, col, matrix[row][col])
+		}
+	}
+	return colorM
+}
+
+// switch sprite style, cached images are dropped so everything redraws in the new one
+func (viz *playfieldViz) SetSpriteStyle(style spriteStyle) {
+	if style == viz.spriteStyle {
+		return
+	}
+	viz.spriteStyle = style
+
+	for y := range viz.fieldState {
+		for x := range viz.fieldState[y] {
+			viz.fieldState[y][x] = playfieldState{}
+		}
+	}
+	viz.nextPillState = [2]playfieldState{}
+	viz.ghostState = [2]playfieldState{}
+}
+
+// the sprite for name in the player's style
+func (viz *playfieldViz) styledSprite(name string) *ebiten.Image {
+	return viz.atlas.Sprite(viz.spriteStyle.spriteName(name))
+}
+
+// a virus and a pill of each color in the player's style, seen through the simulation
+func (viz *playfieldViz) drawColorPreview(image *ebiten.Image, x int, y int, simulation colorSimulation) {
+	size := float64(viz.px(20))
+	colorM := simulation.ColorM()
+
+	colors := [...]drbreakboard.SpaceColor{drbreakboard.Red, drbreakboard.Blue, drbreakboard.Yellow}
+	for i, spaceColor := range colors {
+		virus := drbreakboard.Space{Content: drbreakboard.Virus, Color: spaceColor}
+		pill := drbreakboard.Space{Content: drbreakboard.Pill, Color: spaceColor, Linkage: drbreakboard.Unlinked}
+		pillImage, _ := viz.getPillImage(pill)
+
+		left := float64(x + i*viz.px(24))
+		for row, sprite := range []*ebiten.Image{viz.getVirusImage(virus), pillImage} {
+			imgX, imgY := sprite.Size()
+			geom := ebiten.GeoM{}
+			geom.Scale(size/float64(imgX), size/float64(imgY))
+			geom.Translate(left, float64(y+row*viz.px(24)))
+			image.DrawImage(sprite, &ebiten.DrawImageOptions{GeoM: geom, ColorM: colorM})
+		}
+	}
+}
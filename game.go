@@ -1,15 +1,17 @@
 package main
 
 import (
-	_ "embed"
 	"image"
 	"image/color"
 	_ "image/png"
 	"log"
-	"os"
+	"math"
 
+	"example.com/drbreaktime/assets"
+	"example.com/drbreaktime/match"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
+	zlog "github.com/rs/zerolog/log"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
@@ -17,31 +19,79 @@ import (
 
 // font stuff
 var (
-	//go:embed fonts/Arimo-Regular.ttf
-	BaseTextTTF []byte
+	BaseTextFont  font.Face
+	SmallTextFont font.Face
 
-	BaseTextFont font.Face
+	baseTextFontData *opentype.Font
+	scaledFontMaps   = map[float64]fontMap{} // font maps by scale, made as playfields need them
 )
 
+const baseTextSize = 24
+const smallTextSize = 14
+
 func init() {
 	// load some fonts
-	tt, err := opentype.Parse(BaseTextTTF)
-	if err != nil {
+	if err := setTextFont(assets.BaseTextTTF); err != nil {
 		log.Fatal(err)
 	}
+}
+
+// make the text faces from a ttf, themes can bring their own
+func setTextFont(ttf []byte) error {
+	fontData, err := opentype.Parse(ttf)
+	if err != nil {
+		return err
+	}
+	baseTextFontData = fontData
+
+	BaseTextFont, err = newTextFace(baseTextSize)
+	if err != nil {
+		return err
+	}
 
+	SmallTextFont, err = newTextFace(smallTextSize)
+	if err != nil {
+		return err
+	}
+
+	scaledFontMaps = map[float64]fontMap{}
+	return nil
+}
+
+func newTextFace(size float64) (font.Face, error) {
 	const dpi = 72
-	BaseTextFont, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    24,
+	return opentype.NewFace(baseTextFontData, &opentype.FaceOptions{
+		Size:    size,
 		DPI:     dpi,
 		Hinting: font.HintingVertical,
 	})
-	if err != nil {
-		log.Fatal(err)
+}
+
+// base and small fonts sized for a playfield scale
+// scales are rounded to eighths so nearby sizes share faces
+func scaledFontMap(scale float64) fontMap {
+	scale = math.Round(scale*8) / 8
+	if scale <= 0 || scale == 1 {
+		return fontMap{"base": BaseTextFont, "small": SmallTextFont}
+	}
+
+	if fm, exists := scaledFontMaps[scale]; exists {
+		return fm
+	}
+
+	fm := fontMap{"base": BaseTextFont, "small": SmallTextFont}
+	if face, err := newTextFace(baseTextSize * scale); err == nil {
+		fm["base"] = face
 	}
+	if face, err := newTextFace(smallTextSize * scale); err == nil {
+		fm["small"] = face
+	}
+	scaledFontMaps[scale] = fm
+	return fm
 }
 
 type GameStage int
+type TitleMenuItem int
 type imageMap map[string]image.Image
 type fontMap map[string]font.Face
 
@@ -51,73 +101,147 @@ const (
 	MatchRunning
 	MatchPaused
 	MatchEnded
+	BoardEditor
+	EditorTestPlay
+	Leaderboard
+	TournamentSetup
+	TournamentBracket
+	RebindControls
 )
 
-func getImageFromFilePath(filePath string) (image.Image, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	image, _, err := image.Decode(f)
-	return image, err
-}
-
-func loadImageAndAddToImageMap(imageMap imageMap, filePath string, key string) error {
-	img, err := getImageFromFilePath(filePath)
-	if err != nil {
-		return err
-	}
+const (
+	MenuVersus TitleMenuItem = iota
+	MenuSurvival
+	MenuBoardEditor
+	MenuTournament
+	MenuLeaderboard
+	MenuControls
+	MenuTheme
+	titleMenuItemCount
+)
 
-	imageMap[key] = img
-	return nil
-}
+var titleMenuNames = [...]string{"Versus", "Survival", "Board Editor", "Tournament", "Leaderboard", "Controls", "Theme"}
 
 // Game implements ebiten.Game interface.
 type Game struct {
 	imageMap              imageMap
+	atlas                 *spriteAtlas // the images packed for drawing
 	fontMap               fontMap
 	playfieldViz          []*playfieldViz
-	matchDriver           *matchDriver
+	matchDriver           *match.Driver
 	inputDriver           *inputDriver
 	controllerAssignments map[int]int // map of player index to controller id int
 	currentStage          GameStage
 	playerCount           int
-	lastButtonPresses     map[int][]GamepadEvent
+	lastButtonPresses     map[int][]match.GamepadEvent
 	pausePlayerIndex      int
+	titleSelection        TitleMenuItem
+	boardEditor           *boardEditor
+	profileStore          *profileStore
+	playerSetups          map[int]*playerSetup // map of player index to their join progress
+	leaderboardMessage    string
+	tournament            *tournament      // kept across resets, nil when none is running
+	tournamentSetup       *tournamentSetup // choices while setting up a new tournament
+	tournamentMatch       *tournamentMatch // the match being played, nil outside tournament matches
+	tournamentMessage     string
+	tournamentAbandon     bool // B pressed once on the bracket screen
+	rebindScreen          *rebindScreen
+	autoRepeaters         map[int]*match.AutoRepeater // map of player index to their held direction repeats
+	lostPlayers           map[int]bool                // player indexes whose controller disconnected
+	screenWidth           int
+	screenHeight          int
+	fixedScreen           *ebiten.Image // menus draw here at the base size before scaling
+	themes                []*theme      // the built-in theme first, then any found in the themes folder
+	themeIndex            int
+	recordReplays         bool                  // save a replay of every match
+	recorder              *match.ReplayRecorder // the running match's inputs, nil when not recording
 }
 
-func NewGame() (*Game, error) {
-	game := &Game{imageMap: make(imageMap)}
+// assetDir is a directory of images to use over the built-in ones, empty for none
+func NewGame(assetDir string) (*Game, error) {
+	images, err := assets.LoadBuiltinImages(assetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &Game{imageMap: images}
+	game.atlas = NewSpriteAtlas(withColorVariants(images))
 	game.fontMap = make(fontMap)
 	game.fontMap["base"] = BaseTextFont
+	game.fontMap["small"] = SmallTextFont
+
+	builtin := &theme{name: builtinThemeName, images: imageMap{}}
+	for sprite, img := range game.imageMap {
+		builtin.images[sprite] = img
+	}
+	game.themes = append([]*theme{builtin}, findThemes()...)
+
+	loadGamepadMappings()
+	game.inputDriver = NewInputDriver(loadBindingStore())
+	game.setScreenSize(baseScreenWidth, baseScreenHeight)
+	game.fixedScreen = ebiten.NewImage(baseScreenWidth, baseScreenHeight)
 
-	// TODO: error check
-	// refactor into an asset loader that either just loads everything in the dir
-	// or takes in a list of needed assets
-	loadImageAndAddToImageMap(game.imageMap, "./img/redVirus.png", "redVirus")
-	loadImageAndAddToImageMap(game.imageMap, "./img/blueVirus.png", "blueVirus")
-	loadImageAndAddToImageMap(game.imageMap, "./img/yellowVirus.png", "yellowVirus")
-	loadImageAndAddToImageMap(game.imageMap, "./img/redSingle.png", "redSingle")
-	loadImageAndAddToImageMap(game.imageMap, "./img/blueSingle.png", "blueSingle")
-	loadImageAndAddToImageMap(game.imageMap, "./img/yellowSingle.png", "yellowSingle")
-	loadImageAndAddToImageMap(game.imageMap, "./img/redLinked.png", "redLinked")
-	loadImageAndAddToImageMap(game.imageMap, "./img/yellowLinked.png", "yellowLinked")
-	loadImageAndAddToImageMap(game.imageMap, "./img/blueLinked.png", "blueLinked")
-	loadImageAndAddToImageMap(game.imageMap, "./img/greenPixel.png", "greenPixel")
-
-	game.inputDriver = NewInputDriver()
+	game.profileStore = loadProfileStore()
 
 	game.ResetGame()
 
 	return game, nil
 }
 
+// load saved profiles, falling back to a store that never saves
+// so a bad file isn't overwritten
+func loadProfileStore() *profileStore {
+	profilePath, err := configFilePath(profileFileName)
+	if err != nil {
+		zlog.Printf("no config dir for profiles: %v", err)
+		return NewProfileStore("")
+	}
+
+	store := NewProfileStore(profilePath)
+	if err := store.Load(); err != nil {
+		zlog.Printf("could not load profiles: %v", err)
+		return NewProfileStore("")
+	}
+
+	return store
+}
+
+// load saved bindings, falling back to defaults that never save
+func loadBindingStore() *bindingStore {
+	bindingsPath, err := configFilePath(bindingsFileName)
+	if err != nil {
+		zlog.Printf("no config dir for bindings: %v", err)
+		return NewBindingStore("")
+	}
+
+	store := NewBindingStore(bindingsPath)
+	if err := store.Load(); err != nil {
+		zlog.Printf("could not load bindings: %v", err)
+		return NewBindingStore("")
+	}
+
+	return store
+}
+
+// add the player's own gamepad mappings on top of ebiten's
+func loadGamepadMappings() {
+	mappingsPath, err := configFilePath(gamepadMappingsFileName)
+	if err != nil {
+		zlog.Printf("no config dir for gamepad mappings: %v", err)
+		return
+	}
+
+	if err := LoadGamepadMappings(mappingsPath); err != nil {
+		zlog.Printf("could not load gamepad mappings: %v", err)
+	}
+}
+
 // set game variables to base state with no players or anything
 func (g *Game) ResetGame() {
 	g.playfieldViz = make([]*playfieldViz, 0)
 
-	g.matchDriver = NewMatchDriver()
+	g.matchDriver = match.NewDriver()
+	g.recorder = nil
 
 	g.controllerAssignments = map[int]int{}
 
@@ -125,39 +249,61 @@ func (g *Game) ResetGame() {
 
 	g.playerCount = 0
 
-	g.lastButtonPresses = map[int][]GamepadEvent{}
+	g.lastButtonPresses = map[int][]match.GamepadEvent{}
 
 	g.pausePlayerIndex = 0
 
+	g.titleSelection = MenuVersus
+
+	g.boardEditor = nil
+
+	g.playerSetups = map[int]*playerSetup{}
+	g.inputDriver.SetOverrides(nil)
+
+	g.leaderboardMessage = ""
+
+	g.tournamentSetup = nil
+
+	g.tournamentMatch = nil
+
+	g.tournamentAbandon = false
+
+	g.rebindScreen = nil
+
+	g.autoRepeaters = map[int]*match.AutoRepeater{}
+
+	g.lostPlayers = map[int]bool{}
 }
 
 // Update proceeds the game state.
 // Update is called every tick (1/60 [s] by default).
 func (g *Game) Update() error {
+	updateFullscreen()
+
 	// Write your game's logical update.
-	_, buttonPressEvents := g.inputDriver.UpdateStateAndReturnPresses()
+	connectionChanges, buttonPressEvents := g.inputDriver.UpdateStateAndReturnPresses()
 	g.lastButtonPresses = buttonPressEvents
 
+	g.handleConnectionChanges(connectionChanges)
+
 	switch g.currentStage {
 	case Title:
-
-		// pick up start button
-		for k := range buttonPressEvents {
-			events := buttonPressEvents[k]
-			for _, event := range events {
-				if event == StartJustPressed {
-					g.playerCount = 0
-					g.currentStage = PlayerAssignment
-				}
-			}
-		}
-
+		g.updateTitle(buttonPressEvents)
 	case PlayerAssignment:
 		g.updateReadyForPlayers(buttonPressEvents)
 	case MatchRunning:
-		if !g.matchDriver.matchStarted {
+		if !g.matchDriver.MatchStarted {
 			g.matchDriver.StartMatch()
-		} else if !g.matchDriver.matchEnded {
+			g.resetAutoRepeaters()
+		} else if g.matchDriver.MatchEnded {
+			// let the top out and last clears play out before the results cover the boards
+			g.animateMatchEvents()
+			if g.animationsDone() {
+				g.recordProfileResults()
+				g.recordTournamentResult()
+				g.currentStage = MatchEnded
+			}
+		} else {
 			// all players should have an input device before game start
 			// put the controller event array into the player indexed event map
 			playerIndexInputs := g.getPlayerButtonPresses(buttonPressEvents)
@@ -166,7 +312,7 @@ func (g *Game) Update() error {
 			for playerIndex := 0; playerIndex < g.playerCount; playerIndex += 1 {
 				controllerEvent, exists := playerIndexInputs[playerIndex]
 
-				if exists && checkControllerEventsForEvent(controllerEvent, StartJustPressed) {
+				if exists && match.CheckControllerEventsForEvent(controllerEvent, match.StartJustPressed) {
 					// player paused the game
 					g.currentStage = MatchPaused
 					g.pausePlayerIndex = playerIndex
@@ -174,6 +320,12 @@ func (g *Game) Update() error {
 				}
 			}
 
+			g.recordMatchInputs()
+			g.matchDriver.CountInputs(playerIndexInputs)
+
+			// repeat held directions before the match sees them
+			playerIndexInputs = g.applyAutoRepeat(playerIndexInputs)
+
 			// apply rotations based on button presses
 			g.matchDriver.ApplyInputs(playerIndexInputs)
 
@@ -182,64 +334,218 @@ func (g *Game) Update() error {
 			for i := 0; i < g.playerCount; i++ {
 				g.playfieldViz[i].UpdateBoard(g.matchDriver.GetPlayfield(i),
 					g.matchDriver.GetActivePill(i), g.matchDriver.GetActivePillLocation(i))
+				g.updateGhost(i)
 			}
+			g.animateMatchEvents()
 
-			if g.matchDriver.matchEnded {
-				g.currentStage = MatchEnded
+			if g.matchDriver.MatchEnded {
+				g.saveReplay()
 			}
 		}
 	case MatchPaused:
+		if g.claimLostSlot(buttonPressEvents) {
+			return nil
+		}
+
 		// all players should have an input device before game start
 		// put the controller event array into the player indexed event map
 		playerIndexInputs := g.getPlayerButtonPresses(buttonPressEvents)
 
+		// nobody unpauses until every lost controller is replaced, anyone can still quit
+		if len(g.lostPlayers) > 0 {
+			for _, events := range playerIndexInputs {
+				if match.CheckControllerEventsForEvent(events, match.SelectJustPressed) {
+					g.ResetGame()
+					return nil
+				}
+			}
+			return nil
+		}
+
 		// check for pause button press for unpause
 		pausePlayerEvents, exists := playerIndexInputs[g.pausePlayerIndex]
-		if exists && checkControllerEventsForEvent(pausePlayerEvents, StartJustPressed) {
+		if exists && match.CheckControllerEventsForEvent(pausePlayerEvents, match.StartJustPressed) {
 			// player unpaused, restart the game
 			g.currentStage = MatchRunning
 			return nil
-		} else if exists && checkControllerEventsForEvent(pausePlayerEvents, SelectJustPressed) {
+		} else if exists && match.CheckControllerEventsForEvent(pausePlayerEvents, match.SelectJustPressed) {
 			g.ResetGame()
 		}
 	case MatchEnded:
+		if g.claimLostSlot(buttonPressEvents) {
+			return nil
+		}
+
 		// pick up start button and run a new match
 		for k := range buttonPressEvents {
 			events := buttonPressEvents[k]
 			for _, event := range events {
-				if event == StartJustPressed {
+				if event == match.StartJustPressed && g.tournamentMatch != nil {
+					// back to the bracket for the next match
+					g.ResetGame()
+					g.currentStage = TournamentBracket
+					return nil
+				} else if event == match.StartJustPressed {
 					// start the match again
 					g.matchDriver.ResetAndStartMatch()
+					g.resetAutoRepeaters()
+					for _, pv := range g.playfieldViz {
+						pv.ClearAnimations()
+					}
 					g.currentStage = MatchRunning
+
+					// wait for anyone still without a controller
+					if lost := g.lostPlayerIndexes(); len(lost) > 0 {
+						g.currentStage = MatchPaused
+						g.pausePlayerIndex = lost[0]
+					}
 					return nil
-				} else if event == SelectJustPressed {
+				} else if event == match.SelectJustPressed {
 					// reset the whole game
 					g.ResetGame()
 					return nil
 				}
 			}
 		}
+	case BoardEditor:
+		for controllerId, events := range buttonPressEvents {
+			switch g.boardEditor.Update(events) {
+			case EditorStartTestPlay:
+				g.startEditorTestPlay(controllerId)
+				return nil
+			case EditorExit:
+				g.ResetGame()
+				return nil
+			}
+		}
+	case EditorTestPlay:
+		g.updateEditorTestPlay(buttonPressEvents)
+	case Leaderboard:
+		g.updateLeaderboard(buttonPressEvents)
+	case TournamentSetup:
+		g.updateTournamentSetup(buttonPressEvents)
+	case TournamentBracket:
+		g.updateTournamentBracket(buttonPressEvents)
+	case RebindControls:
+		g.updateRebind(buttonPressEvents)
 	}
 
 	return nil
 }
 
-func checkControllerEventsForEvent(controllerEvent []GamepadEvent, targetEvent GamepadEvent) bool {
-	if controllerEvent == nil {
-		return false
+func (g *Game) updateTitle(buttonPressEvents map[int][]match.GamepadEvent) {
+	for controllerId, events := range buttonPressEvents {
+		for _, event := range events {
+			switch event {
+			case match.UpJustPressed:
+				g.titleSelection = (g.titleSelection + titleMenuItemCount - 1) % titleMenuItemCount
+			case match.DownJustPressed:
+				g.titleSelection = (g.titleSelection + 1) % titleMenuItemCount
+			case match.StartJustPressed:
+				switch g.titleSelection {
+				case MenuVersus:
+					g.playerCount = 0
+					g.currentStage = PlayerAssignment
+				case MenuSurvival:
+					g.matchDriver.SetMode(match.SurvivalMode)
+					g.playerCount = 0
+					g.currentStage = PlayerAssignment
+				case MenuBoardEditor:
+					g.boardEditor = NewBoardEditor(g.atlas, g.fontMap, editorBoardPath)
+					g.currentStage = BoardEditor
+				case MenuTournament:
+					g.enterTournament()
+				case MenuLeaderboard:
+					g.currentStage = Leaderboard
+				case MenuControls:
+					if g.inputDriver.IsTouchController(controllerId) {
+						continue
+					}
+					// rebind whichever controller picked it
+					g.rebindScreen = NewRebindScreen(controllerId, g.inputDriver.GetBindings(controllerId),
+						g.inputDriver.GetDeadzone(controllerId))
+					g.currentStage = RebindControls
+				case MenuTheme:
+					g.applyTheme((g.themeIndex + 1) % len(g.themes))
+				}
+				return
+			}
+		}
 	}
+}
 
-	for _, event := range controllerEvent {
-		if event == targetEvent {
-			return true
+// play the editor's board solo with the controller that asked for it
+func (g *Game) startEditorTestPlay(controllerId int) {
+	g.matchDriver = match.NewDriver()
+	g.matchDriver.AddPlayer()
+	if err := g.matchDriver.SetPlayerBoard(0, g.boardEditor.board); err != nil {
+		g.boardEditor.message = err.Error()
+		g.matchDriver = match.NewDriver()
+		return
+	}
+	g.matchDriver.StartMatch()
+
+	g.playfieldViz = []*playfieldViz{NewPlayfieldViz(g.atlas, g.fontMap)}
+	g.layoutPlayfields()
+	g.controllerAssignments = map[int]int{0: controllerId}
+	g.playerCount = 1
+	g.resetAutoRepeaters()
+
+	g.currentStage = EditorTestPlay
+}
+
+func (g *Game) updateEditorTestPlay(buttonPressEvents map[int][]match.GamepadEvent) {
+	playerIndexInputs := g.getPlayerButtonPresses(buttonPressEvents)
+
+	// start or select stops the test
+	if match.CheckControllerEventsForEvent(playerIndexInputs[0], match.StartJustPressed) ||
+		match.CheckControllerEventsForEvent(playerIndexInputs[0], match.SelectJustPressed) {
+		g.endEditorTestPlay("Test stopped")
+		return
+	}
+
+	// stay on the board until the top out or last clears have played out
+	if g.matchDriver.MatchEnded {
+		g.animateMatchEvents()
+		if !g.animationsDone() {
+			return
 		}
+
+		finishes := g.matchDriver.PlayerFinishes
+		if len(finishes) > 0 && finishes[0].Result == match.Cleared {
+			g.endEditorTestPlay("Board cleared!")
+		} else {
+			g.endEditorTestPlay("Topped out")
+		}
+		return
 	}
 
-	return false
+	g.matchDriver.CountInputs(playerIndexInputs)
+	playerIndexInputs = g.applyAutoRepeat(playerIndexInputs)
+	g.matchDriver.ApplyInputs(playerIndexInputs)
+	g.matchDriver.ApplyTick(playerIndexInputs)
+
+	g.playfieldViz[0].UpdateBoard(g.matchDriver.GetPlayfield(0),
+		g.matchDriver.GetActivePill(0), g.matchDriver.GetActivePillLocation(0))
+	g.updateGhost(0)
+	g.animateMatchEvents()
+}
+
+// throw away the test match and go back to editing
+func (g *Game) endEditorTestPlay(message string) {
+	g.boardEditor.message = message
+
+	g.matchDriver = match.NewDriver()
+	g.playfieldViz = make([]*playfieldViz, 0)
+	g.controllerAssignments = map[int]int{}
+	g.playerCount = 0
+	g.inputDriver.SetOverrides(nil)
+
+	g.currentStage = BoardEditor
 }
 
-func (g *Game) getPlayerButtonPresses(buttonPressEvents map[int][]GamepadEvent) map[int][]GamepadEvent {
-	playerIndexInputs := map[int][]GamepadEvent{}
+func (g *Game) getPlayerButtonPresses(buttonPressEvents map[int][]match.GamepadEvent) map[int][]match.GamepadEvent {
+	playerIndexInputs := map[int][]match.GamepadEvent{}
 	for playerIndex := range g.controllerAssignments {
 		controllerId := g.controllerAssignments[playerIndex]
 		controllerEvent, exists := buttonPressEvents[controllerId]
@@ -251,11 +557,21 @@ func (g *Game) getPlayerButtonPresses(buttonPressEvents map[int][]GamepadEvent)
 	return playerIndexInputs
 }
 
-func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]GamepadEvent) {
+// assign the new controller to a new player
+func (g *Game) addPlayer(controllerId int) {
+	g.playfieldViz = append(g.playfieldViz, NewPlayfieldViz(g.atlas, g.fontMap))
+	g.layoutPlayfields()
+	g.controllerAssignments[g.playerCount] = controllerId
+	g.playerSetups[g.playerCount] = NewPlayerSetup()
+	g.matchDriver.AddPlayer()
+	g.playerCount += 1
+}
+
+func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]match.GamepadEvent) {
 	// pick up new players
 	for controllerId, events := range buttonPressEvents {
 		for _, event := range events {
-			if event == StartJustPressed {
+			if event == match.StartJustPressed {
 				// map to player if controller not currently mapped
 				playerIndex := -1
 				for assignedIndex, assignedId := range g.controllerAssignments {
@@ -264,20 +580,26 @@ func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]GamepadEvent) {
 						break
 					}
 				}
+				// survival is solo, only the first controller joins
+				if playerIndex == -1 && g.matchDriver.Mode == match.SurvivalMode && g.playerCount >= 1 {
+					continue
+				}
+				// tournament matches are one on one
+				if playerIndex == -1 && g.tournamentMatch != nil && g.playerCount >= 2 {
+					continue
+				}
+				if playerIndex == -1 && g.playerCount >= match.MaxPlayers {
+					continue
+				}
+
 				if playerIndex == -1 {
-					// assign the new controller to a new player
-					pv := NewPlayfieldViz(g.imageMap, g.fontMap)
-					pv.SetPixelSizeAndOffset(160, 480, g.playerCount*160, 0)
-					g.playfieldViz = append(g.playfieldViz, pv)
-					g.controllerAssignments[g.playerCount] = controllerId
-					g.matchDriver.AddPlayer()
-					g.playerCount += 1
+					g.addPlayer(controllerId)
 				}
 			}
 		}
 	}
 
-	// handle level setting and ready for joined players
+	// handle profile, level setting and ready for joined players
 	for controllerId, events := range buttonPressEvents {
 		playerIndex := -1
 		for assignedIndex, assignedId := range g.controllerAssignments {
@@ -292,22 +614,21 @@ func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]GamepadEvent) {
 			continue
 		}
 
-		ready, _ := g.matchDriver.GetPlayerReady(playerIndex)
+		// select goes back to the title from any step
+		if match.CheckControllerEventsForEvent(events, match.SelectJustPressed) {
+			g.ResetGame()
+			return
+		}
 
-		// handle level setting and ready buttons
-		for _, event := range events {
-			if event == UpJustPressed && !ready {
-				_ = g.matchDriver.ChangeLevel(playerIndex, 1)
-			} else if event == DownJustPressed && !ready {
-				_ = g.matchDriver.ChangeLevel(playerIndex, -1)
-			} else if event == PrimaryJustPressed {
-				_ = g.matchDriver.SetPlayerReady(playerIndex, true)
-			} else if event == SecondaryJustPressed {
-				_ = g.matchDriver.SetPlayerReady(playerIndex, false)
-			} else if event == SelectJustPressed {
-				g.ResetGame()
-				return
-			}
+		switch g.playerSetups[playerIndex].phase {
+		case ChoosingProfile:
+			g.updateProfileChoice(playerIndex, events)
+		case EnteringName:
+			g.updateNameEntry(playerIndex, events)
+		case ChoosingLevel:
+			g.updateLevelChoice(playerIndex, events)
+		case ChoosingOptions:
+			g.updateOptionChoice(playerIndex, events)
 		}
 	}
 
@@ -316,6 +637,11 @@ func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]GamepadEvent) {
 		return
 	}
 
+	// both called players have to be there
+	if g.tournamentMatch != nil && g.playerCount < 2 {
+		return
+	}
+
 	// check if all players ready
 	allPlayersReady := true
 	for i := 0; i < g.playerCount; i++ {
@@ -328,6 +654,7 @@ func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]GamepadEvent) {
 	if allPlayersReady {
 		// start the match
 		g.matchDriver.StartMatch()
+		g.resetAutoRepeaters()
 		g.currentStage = MatchRunning
 	}
 }
@@ -335,25 +662,75 @@ func (g *Game) updateReadyForPlayers(buttonPressEvents map[int][]GamepadEvent) {
 // Draw draws the game screen.
 // Draw is called every frame (typically 1/60[s] for 60Hz display).
 func (g *Game) Draw(screen *ebiten.Image) {
+	g.drawBackground(screen)
+
+	if g.currentStage.usesPlayfieldLayout() {
+		g.drawStage(screen)
+	} else {
+		g.fixedScreen.Clear()
+		g.drawStage(g.fixedScreen)
+		drawScaledToFit(screen, g.fixedScreen)
+	}
+
+	g.drawTouchButtons(screen)
+}
+
+func (g *Game) drawStage(screen *ebiten.Image) {
 	switch g.currentStage {
 	case Title:
-		text.Draw(screen, "Dr Breaktime!\n\nPress Start", BaseTextFont, 100, 100, color.RGBA{128, 128, 128, 255})
+		text.Draw(screen, "Dr Breaktime!", BaseTextFont, 100, 100, color.RGBA{128, 128, 128, 255})
+		for i, name := range titleMenuNames {
+			marker := "  "
+			if TitleMenuItem(i) == g.titleSelection {
+				marker = "> "
+			}
+			if TitleMenuItem(i) == MenuTheme {
+				name += ": " + g.themes[g.themeIndex].name
+			}
+			text.Draw(screen, marker+name, BaseTextFont, 100, 160+i*30, color.RGBA{128, 128, 128, 255})
+		}
+		text.Draw(screen, "Press Start", BaseTextFont, 100, 200+int(titleMenuItemCount)*30, color.RGBA{128, 128, 128, 255})
 	case PlayerAssignment:
 		for playerIndex, pv := range g.playfieldViz {
+			setup := g.playerSetups[playerIndex]
+			switch setup.phase {
+			case ChoosingProfile:
+				pv.DrawProfileChoiceToImage(screen, g.profileOptions(), setup.profileCursor, setup.message)
+				continue
+			case EnteringName:
+				pv.DrawNameEntryToImage(screen, setup.name, nameChars[setup.nameChar], setup.message)
+				continue
+			case ChoosingOptions:
+				names, values := setup.optionLines()
+				pv.DrawOptionsToImage(screen, names, values, setup.optionCursor, setup.options.ColorPreview)
+				continue
+			}
+
 			level, err := g.matchDriver.GetLevel(playerIndex)
 			if err != nil {
 				panic("no level for drawn player during player assignment")
 			}
+			speed, err := g.matchDriver.GetSpeed(playerIndex)
+			if err != nil {
+				panic("no speed for drawn player during player assignment")
+			}
 			ready, err := g.matchDriver.GetPlayerReady(playerIndex)
 			if err != nil {
 				panic("no ready value present during player assignment")
 			}
-			pv.DrawWaitingPlayerToImage(screen, level, ready)
+			pv.DrawWaitingPlayerToImage(screen, setup.DisplayName(), level, speed, ready)
 		}
 	case MatchRunning:
 		for playerIndex, viz := range g.playfieldViz {
 			viz.DrawBoardToImage(screen)
 
+			if g.matchDriver.Mode == match.SurvivalMode {
+				score, _ := g.matchDriver.GetScore(playerIndex)
+				elapsed, untilRise, _ := g.matchDriver.GetSurvivalTimers(playerIndex)
+				viz.DrawSurvivalStatusToImage(screen, score, elapsed, untilRise, g.matchDriver.GetNextPill(playerIndex))
+				continue
+			}
+
 			numVirii, _ := g.matchDriver.GetViriiRemaining(playerIndex)
 			dropInbound, _ := g.matchDriver.GetIsDropInbound(playerIndex)
 			viz.DrawStatusToImage(screen, numVirii, g.matchDriver.GetNextPill(playerIndex),
@@ -361,13 +738,43 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	case MatchPaused:
 		for playerIndex, pv := range g.playfieldViz {
-			pv.DrawPausedToImage(screen, g.pausePlayerIndex == playerIndex)
+			if g.lostPlayers[playerIndex] {
+				pv.DrawControllerLostToImage(screen, playerIndex)
+				continue
+			}
+			pv.DrawPausedToImage(screen, g.pausePlayerIndex == playerIndex && len(g.lostPlayers) == 0)
 		}
 	case MatchEnded:
 		for playerIndex, pv := range g.playfieldViz {
-			matchWinner := playerIndex == g.matchDriver.winner
+			if g.matchDriver.Mode == match.SurvivalMode {
+				score, _ := g.matchDriver.GetScore(playerIndex)
+				elapsed, _, _ := g.matchDriver.GetSurvivalTimers(playerIndex)
+				pv.DrawSurvivalResultToImage(screen, score, elapsed)
+				continue
+			}
+
+			matchWinner := playerIndex == g.matchDriver.Winner
 			pv.DrawResultToImage(screen, matchWinner, false)
+
+			stats, _ := g.matchDriver.GetPlayerStats(playerIndex)
+			pv.DrawStatsToImage(screen, stats)
 		}
+	case BoardEditor:
+		g.boardEditor.Draw(screen)
+	case EditorTestPlay:
+		viz := g.playfieldViz[0]
+		viz.DrawBoardToImage(screen)
+
+		numVirii, _ := g.matchDriver.GetViriiRemaining(0)
+		viz.DrawStatusToImage(screen, numVirii, g.matchDriver.GetNextPill(0), false)
+	case Leaderboard:
+		g.drawLeaderboard(screen)
+	case TournamentSetup:
+		g.drawTournamentSetup(screen)
+	case TournamentBracket:
+		g.drawTournamentBracket(screen)
+	case RebindControls:
+		g.drawRebind(screen)
 	}
 
 	// Write your game's rendering.
@@ -375,7 +782,15 @@ func (g *Game) Draw(screen *ebiten.Image) {
 }
 
 // Layout takes the outside size (e.g., the window size) and returns the (logical) screen size.
-// If you don't have to adjust the screen size with the outside size, just return a fixed size.
+// The screen is the window size, playfields are laid out to fit it.
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return 640, 480
+	if outsideWidth < minScreenWidth {
+		outsideWidth = minScreenWidth
+	}
+	if outsideHeight < minScreenHeight {
+		outsideHeight = minScreenHeight
+	}
+
+	g.setScreenSize(outsideWidth, outsideHeight)
+	return outsideWidth, outsideHeight
 }
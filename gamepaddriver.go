@@ -0,0 +1,33 @@
+package main
+
+import (
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// one connected gamepad, made on connect and dropped on disconnect
+type gamepadDriver struct {
+	id       ebiten.GamepadID
+	bindings func(controllerId int) deviceBindings
+	deadzone func(controllerId int) float64
+	held     [match.InputActionCount]bool // actions held last frame
+}
+
+func NewGamepadDriver(id ebiten.GamepadID, bindings func(controllerId int) deviceBindings,
+	deadzone func(controllerId int) float64) *gamepadDriver {
+	gd := &gamepadDriver{id: id, bindings: bindings, deadzone: deadzone}
+	return gd
+}
+
+func (driver *gamepadDriver) DeviceId() int {
+	return int(driver.id)
+}
+
+func (driver *gamepadDriver) Kind() match.DeviceKind {
+	return match.GamepadDevice
+}
+
+func (driver *gamepadDriver) Poll(tick int) []match.InputEvent {
+	pressed := driver.bindings(driver.DeviceId()).pressedActions(driver.id, driver.deadzone(driver.DeviceId()))
+	return match.ActionsToInputEvents(driver, tick, pressed, &driver.held)
+}
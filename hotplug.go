@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sort"
+
+	"example.com/drbreaktime/match"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// player index using the controller, -1 if it isn't assigned
+func (g *Game) getControllerPlayer(controllerId int) int {
+	for playerIndex, assignedId := range g.controllerAssignments {
+		if assignedId == controllerId {
+			return playerIndex
+		}
+	}
+	return -1
+}
+
+// deal with gamepads coming and going
+func (g *Game) handleConnectionChanges(connectionChanges map[int]match.GamepadEvent) {
+	for controllerId, change := range connectionChanges {
+		if change != match.GamepadDisconnected {
+			continue
+		}
+
+		playerIndex := g.getControllerPlayer(controllerId)
+		if playerIndex < 0 {
+			continue
+		}
+
+		switch g.currentStage {
+		case PlayerAssignment:
+			g.removePlayer(playerIndex)
+		case EditorTestPlay:
+			g.endEditorTestPlay("Controller lost")
+		case MatchRunning, MatchPaused, MatchEnded:
+			// hold the slot for whoever picks it up
+			zlog.Printf("player %d controller lost", playerIndex+1)
+			delete(g.controllerAssignments, playerIndex)
+			g.lostPlayers[playerIndex] = true
+
+			if g.currentStage == MatchRunning {
+				g.currentStage = MatchPaused
+				g.pausePlayerIndex = playerIndex
+			}
+		}
+	}
+}
+
+// lost player slots, lowest first
+func (g *Game) lostPlayerIndexes() []int {
+	lost := make([]int, 0)
+	for playerIndex := range g.lostPlayers {
+		lost = append(lost, playerIndex)
+	}
+	sort.Ints(lost)
+	return lost
+}
+
+// start on an unassigned device takes over the lowest lost slot
+// returns true if a slot was claimed
+func (g *Game) claimLostSlot(buttonPressEvents map[int][]match.GamepadEvent) bool {
+	lost := g.lostPlayerIndexes()
+	if len(lost) == 0 {
+		return false
+	}
+
+	for controllerId, events := range buttonPressEvents {
+		if g.getControllerPlayer(controllerId) >= 0 || !match.CheckControllerEventsForEvent(events, match.StartJustPressed) {
+			continue
+		}
+
+		playerIndex := lost[0]
+		zlog.Printf("player %d claimed by controller %d", playerIndex+1, controllerId)
+		g.controllerAssignments[playerIndex] = controllerId
+		delete(g.lostPlayers, playerIndex)
+		g.applyProfileBindings()
+
+		// the new controller can unpause once everyone's back
+		g.pausePlayerIndex = playerIndex
+		return true
+	}
+
+	return false
+}
+
+// take a player out during player assignment, later players move down a slot
+func (g *Game) removePlayer(playerIndex int) {
+	if err := g.matchDriver.RemovePlayer(playerIndex); err != nil {
+		zlog.Printf("could not remove player %d: %v", playerIndex+1, err)
+		return
+	}
+
+	g.playfieldViz = append(g.playfieldViz[:playerIndex], g.playfieldViz[playerIndex+1:]...)
+	g.layoutPlayfields()
+
+	for i := playerIndex; i < g.playerCount-1; i++ {
+		g.controllerAssignments[i] = g.controllerAssignments[i+1]
+		g.playerSetups[i] = g.playerSetups[i+1]
+	}
+	delete(g.controllerAssignments, g.playerCount-1)
+	delete(g.playerSetups, g.playerCount-1)
+
+	g.playerCount--
+	g.autoRepeaters = map[int]*match.AutoRepeater{}
+	g.applyProfileBindings()
+}
@@ -1,119 +1,304 @@
 package main
 
 import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"example.com/drbreaktime/match"
 	"github.com/rs/zerolog/log"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-type GamepadEvent int
-
-const (
-	GamepadConnected GamepadEvent = iota
-	GamepadDisconnected
-	PrimaryJustPressed
-	SecondaryJustPressed
-	StartJustPressed
-	SelectJustPressed
-	LeftPressed
-	RightPressed
-	DownPressed
-	LeftJustPressed
-	RightJustPressed
-	DownJustPressed
-	UpJustPressed
-)
+// fake controller ids that carry keyboard input, one per keyboard controller counting up from here
+const keyboardControllerId = 21985
+
+// extra SDL gamepad mappings for pads ebiten doesn't know, same format as SDL's gamecontrollerdb.txt
+const gamepadMappingsFileName = "gamecontrollerdb.txt"
 
 type inputDriver struct {
-	gamepadIDsBuf  []ebiten.GamepadID
-	gamepadIDs     map[ebiten.GamepadID]struct{}
-	keyboardDriver *keyboardDriver
+	gamepadIDsBuf   []ebiten.GamepadID
+	gamepadDrivers  map[ebiten.GamepadID]*gamepadDriver
+	keyboardDrivers []*keyboardDriver
+	touchDriver     *touchDriver
+	extraSources    []match.InputSource // replays and anything else that isn't a local device
+	bindingStore    *bindingStore
+	keysBuf         []ebiten.Key
+	captureAxes     []int // axis directions already held on the gamepad being rebound
+	tick            int
+	lastEvents      []match.InputEvent
+	defaults        map[int]deviceBindings // default bindings by controller, kept so polling doesn't rebuild them
+	overrides       map[int]deviceBindings // bindings by controller from the profile playing on it
 }
 
-func NewInputDriver() *inputDriver {
+func NewInputDriver(bindingStore *bindingStore) *inputDriver {
 	id := &inputDriver{}
-	id.gamepadIDs = map[ebiten.GamepadID]struct{}{}
-	id.keyboardDriver = NewKeyboardDriver()
+	id.gamepadDrivers = map[ebiten.GamepadID]*gamepadDriver{}
+	id.bindingStore = bindingStore
+	id.keyboardDrivers = make([]*keyboardDriver, bindingStore.KeyboardControllers())
+	for i := range id.keyboardDrivers {
+		id.keyboardDrivers[i] = NewKeyboardDriver(keyboardControllerId+i, id.GetBindings)
+	}
+	id.touchDriver = NewTouchDriver()
 	return id
 }
 
 // returns connection change events, button press events
-func (driver *inputDriver) UpdateStateAndReturnPresses() (map[int]GamepadEvent, map[int][]GamepadEvent) {
-	if driver.gamepadIDs == nil {
-		driver.gamepadIDs = map[ebiten.GamepadID]struct{}{}
+func (driver *inputDriver) UpdateStateAndReturnPresses() (map[int]match.GamepadEvent, map[int][]match.GamepadEvent) {
+	if driver.gamepadDrivers == nil {
+		driver.gamepadDrivers = map[ebiten.GamepadID]*gamepadDriver{}
 	}
 
-	connectionChanges := map[int]GamepadEvent{}
+	connectionChanges := map[int]match.GamepadEvent{}
 
 	// Log the gamepad connection events.
 	driver.gamepadIDsBuf = inpututil.AppendJustConnectedGamepadIDs(driver.gamepadIDsBuf[:0])
 	for _, id := range driver.gamepadIDsBuf {
 		log.Printf("gamepad connected: id: %d, SDL ID: %s", id, ebiten.GamepadSDLID(id))
-		driver.gamepadIDs[id] = struct{}{}
+		driver.gamepadDrivers[id] = NewGamepadDriver(id, driver.GetBindings, driver.GetDeadzone)
+		delete(driver.defaults, int(id))
 
 		// report the gamepad connected
-		connectionChanges[int(id)] = GamepadConnected
+		connectionChanges[int(id)] = match.GamepadConnected
 	}
 
-	for id := range driver.gamepadIDs {
+	for id := range driver.gamepadDrivers {
 		if inpututil.IsGamepadJustDisconnected(id) {
 			log.Printf("gamepad disconnected: id: %d", id)
-			delete(driver.gamepadIDs, id)
+			delete(driver.gamepadDrivers, id)
 
 			// mark this gamepad disconnected
-			connectionChanges[int(id)] = GamepadDisconnected
+			connectionChanges[int(id)] = match.GamepadDisconnected
 		}
 	}
 
-	buttonEvents := map[int][]GamepadEvent{}
+	touchWasActive := driver.touchDriver.Active()
 
-	for id := range driver.gamepadIDs {
-		buttonEventList := make([]GamepadEvent, 0)
+	driver.tick++
+	driver.lastEvents = make([]match.InputEvent, 0)
+	buttonEvents := map[int][]match.GamepadEvent{}
 
+	// every source gets a fake controller, keyboards included
+	// TODO: add connect on first keypress later
+	for _, source := range driver.sources() {
+		events := source.Poll(driver.tick)
+		driver.lastEvents = append(driver.lastEvents, events...)
+		buttonEvents[source.DeviceId()] = match.GamepadEventsFromInput(events)
+	}
+
+	// touch connects the first time it's used so it doesn't join menus by itself
+	if !touchWasActive && driver.touchDriver.Active() {
+		log.Printf("touch connected")
+		connectionChanges[touchControllerId] = match.GamepadConnected
+	}
+
+	return connectionChanges, buttonEvents
+}
+
+func (driver *inputDriver) sources() []match.InputSource {
+	sources := make([]match.InputSource, 0, len(driver.gamepadDrivers)+len(driver.keyboardDrivers)+len(driver.extraSources)+1)
+	for _, gd := range driver.gamepadDrivers {
+		sources = append(sources, gd)
+	}
+	for _, kd := range driver.keyboardDrivers {
+		sources = append(sources, kd)
+	}
+	sources = append(sources, driver.touchDriver)
+	return append(sources, driver.extraSources...)
+}
+
+// add a source that isn't a local device, its id shouldn't clash with a gamepad or keyboard
+func (driver *inputDriver) AddSource(source match.InputSource) {
+	driver.extraSources = append(driver.extraSources, source)
+}
+
+// every input event from the last update, for recording
+func (driver *inputDriver) LastEvents() []match.InputEvent {
+	return driver.lastEvents
+}
+
+func (driver *inputDriver) IsKeyboardController(controllerId int) bool {
+	return controllerId >= keyboardControllerId && controllerId < keyboardControllerId+len(driver.keyboardDrivers)
+}
+
+// touch and mouse gestures can't be rebound
+func (driver *inputDriver) IsTouchController(controllerId int) bool {
+	return controllerId == touchControllerId
+}
+
+// name bindings are saved under, gamepads of the same model share bindings
+func (driver *inputDriver) DeviceName(controllerId int) string {
+	if driver.IsKeyboardController(controllerId) {
+		return keyboardDeviceName(controllerId - keyboardControllerId)
+	}
+	return ebiten.GamepadSDLID(ebiten.GamepadID(controllerId))
+}
+
+// load extra gamepad mappings, a missing file is fine
+// pads with a mapping get the standard layout bindings
+func LoadGamepadMappings(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = ebiten.UpdateStandardGamepadLayoutMappings(string(data))
+	return err
+}
+
+// start watching a controller for a new binding
+// sticks already pushed when it starts don't count until they're let go
+func (driver *inputDriver) StartCapture(controllerId int) {
+	driver.captureAxes = nil
+	if !driver.IsKeyboardController(controllerId) {
+		driver.captureAxes = stickDirections(ebiten.GamepadID(controllerId), driver.GetDeadzone(controllerId))
+	}
+}
+
+// first key, button or stick direction pressed on the controller this frame
+func (driver *inputDriver) CaptureBinding(controllerId int) (inputBinding, bool) {
+	if driver.IsKeyboardController(controllerId) {
+		driver.keysBuf = inpututil.AppendPressedKeys(driver.keysBuf[:0])
+		for _, key := range driver.keysBuf {
+			if inpututil.KeyPressDuration(key) == 1 {
+				return inputBinding{kind: keyBinding, code: int(key)}, true
+			}
+		}
+		return inputBinding{}, false
+	}
+
+	id := ebiten.GamepadID(controllerId)
+	standard := ebiten.IsStandardGamepadLayoutAvailable(id)
+
+	// pads with a known layout bind standard buttons so the binding works on any model
+	if standard {
+		for b := ebiten.StandardGamepadButton(0); b <= ebiten.StandardGamepadButtonMax; b++ {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, b) {
+				log.Printf("captured standard button: id: %d, button: %d", id, b)
+				return inputBinding{kind: standardButtonBinding, code: int(b)}, true
+			}
+		}
+	} else {
 		maxButton := ebiten.GamepadButton(ebiten.GamepadButtonCount(id))
 		for b := ebiten.GamepadButton(0); b < maxButton; b++ {
-			// Log button events.
 			if inpututil.IsGamepadButtonJustPressed(id, b) {
-				log.Printf("button pressed: id: %d, button: %d", id, b)
-				if b == 0 {
-					buttonEventList = append(buttonEventList, PrimaryJustPressed)
-				} else if b == 1 || b == 2 {
-					buttonEventList = append(buttonEventList, SecondaryJustPressed)
-				} else if b == 6 {
-					buttonEventList = append(buttonEventList, SelectJustPressed)
-				} else if b == 7 {
-					buttonEventList = append(buttonEventList, StartJustPressed)
-				} else if b == 13 {
-					buttonEventList = append(buttonEventList, LeftJustPressed)
-				} else if b == 11 {
-					buttonEventList = append(buttonEventList, RightJustPressed)
-				} else if b == 12 {
-					buttonEventList = append(buttonEventList, DownJustPressed)
-				} else if b == 10 {
-					buttonEventList = append(buttonEventList, UpJustPressed)
-				}
+				log.Printf("captured button: id: %d, button: %d", id, b)
+				return inputBinding{kind: buttonBinding, code: int(b)}, true
 			}
+		}
+	}
 
-			if ebiten.IsGamepadButtonPressed(id, b) {
-				log.Printf("button pressed: id: %d, button: %d", id, b)
-				if b == 13 {
-					buttonEventList = append(buttonEventList, LeftPressed)
-				} else if b == 11 {
-					buttonEventList = append(buttonEventList, RightPressed)
-				} else if b == 12 {
-					buttonEventList = append(buttonEventList, DownPressed)
-				}
-			}
+	axisKind := axisBinding
+	if standard {
+		axisKind = standardAxisBinding
+	}
+
+	directions := stickDirections(id, driver.GetDeadzone(controllerId))
+	for axis, direction := range directions {
+		if direction == 0 || (axis < len(driver.captureAxes) && driver.captureAxes[axis] == direction) {
+			continue
 		}
+		log.Printf("captured axis: id: %d, axis: %d, direction: %d", id, axis, direction)
+		return inputBinding{kind: axisKind, code: axis, direction: direction}, true
+	}
+	driver.captureAxes = directions
+
+	return inputBinding{}, false
+}
 
-		buttonEvents[int(id)] = buttonEventList
+// which way each axis is pushed past the deadzone, 0 for neither
+// standard layout axes when the pad has them, raw axes otherwise
+func stickDirections(id ebiten.GamepadID, deadzone float64) []int {
+	standard := ebiten.IsStandardGamepadLayoutAvailable(id)
+
+	axisCount := ebiten.GamepadAxisCount(id)
+	if standard {
+		axisCount = int(ebiten.StandardGamepadAxisMax) + 1
 	}
 
-	// get kbevents and create a fake controller to carry keyboard input
-	// TODO: add connect on first keypress later
-	kbEvents := driver.keyboardDriver.GetKeyboardAsGamepadEvents()
-	buttonEvents[21985] = kbEvents
+	directions := make([]int, axisCount)
+	for axis := range directions {
+		value := ebiten.GamepadAxisValue(id, axis)
+		if standard {
+			value = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxis(axis))
+		}
 
-	return connectionChanges, buttonEvents
+		if value > deadzone {
+			directions[axis] = 1
+		} else if value < -deadzone {
+			directions[axis] = -1
+		}
+	}
+	return directions
+}
+
+// the profile's bindings for the controller if one is playing on it,
+// otherwise saved bindings for the controller, or the defaults for its kind
+func (driver *inputDriver) GetBindings(controllerId int) deviceBindings {
+	if bindings, overridden := driver.overrides[controllerId]; overridden {
+		return bindings
+	}
+
+	return driver.deviceBindings(controllerId)
+}
+
+// the controller's own bindings, ignoring any profile
+func (driver *inputDriver) deviceBindings(controllerId int) deviceBindings {
+	if bindings, saved := driver.bindingStore.ForDevice(driver.DeviceName(controllerId)); saved {
+		return bindings
+	}
+
+	if driver.defaults == nil {
+		driver.defaults = map[int]deviceBindings{}
+	}
+	bindings, cached := driver.defaults[controllerId]
+	if !cached {
+		bindings = driver.DefaultBindings(controllerId)
+		driver.defaults[controllerId] = bindings
+	}
+	return bindings
+}
+
+// replace the actions in each controller's overrides, by controller id, with the ones given
+// controllers left out go back to their own bindings
+func (driver *inputDriver) SetOverrides(overrides map[int]deviceBindings) {
+	driver.overrides = map[int]deviceBindings{}
+	for controllerId, actions := range overrides {
+		bindings := driver.deviceBindings(controllerId).clone()
+		for action, list := range actions {
+			bindings[action] = list
+		}
+		driver.overrides[controllerId] = bindings
+	}
+}
+
+func (driver *inputDriver) DefaultBindings(controllerId int) deviceBindings {
+	if driver.IsKeyboardController(controllerId) {
+		return defaultKeyboardBindings(controllerId - keyboardControllerId)
+	}
+	if ebiten.IsStandardGamepadLayoutAvailable(ebiten.GamepadID(controllerId)) {
+		return defaultStandardGamepadBindings()
+	}
+	return defaultGamepadBindings()
+}
+
+func (driver *inputDriver) GetDeadzone(controllerId int) float64 {
+	return driver.bindingStore.Deadzone(driver.DeviceName(controllerId))
+}
+
+func (driver *inputDriver) SetDeadzone(controllerId int, deadzone float64) {
+	driver.bindingStore.SetDeadzone(driver.DeviceName(controllerId), deadzone)
+}
+
+func (driver *inputDriver) SetBindings(controllerId int, bindings deviceBindings) {
+	driver.bindingStore.SetDevice(driver.DeviceName(controllerId), bindings)
+}
+
+func (driver *inputDriver) SaveBindings() error {
+	return driver.bindingStore.Save()
 }
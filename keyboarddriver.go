@@ -1,64 +1,29 @@
 package main
 
-import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-)
+import "example.com/drbreaktime/match"
 
+// one keyboard controller, reads whichever keys are bound to it
 type keyboardDriver struct {
+	deviceId int
+	bindings func(controllerId int) deviceBindings
+	held     [match.InputActionCount]bool // actions held last frame
 }
 
-func NewKeyboardDriver() *keyboardDriver {
-	id := &keyboardDriver{}
+func NewKeyboardDriver(deviceId int, bindings func(controllerId int) deviceBindings) *keyboardDriver {
+	id := &keyboardDriver{deviceId: deviceId, bindings: bindings}
 	return id
 }
 
-func (driver *keyboardDriver) GetKeyboardAsGamepadEvents() []GamepadEvent {
-	buttonEventList := make([]GamepadEvent, 16)
-
-	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
-		buttonEventList = append(buttonEventList, DownPressed)
-	}
-
-	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
-		buttonEventList = append(buttonEventList, LeftPressed)
-	}
-
-	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
-		buttonEventList = append(buttonEventList, RightPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
-		buttonEventList = append(buttonEventList, RightJustPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
-		buttonEventList = append(buttonEventList, LeftJustPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
-		buttonEventList = append(buttonEventList, DownJustPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-		buttonEventList = append(buttonEventList, UpJustPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
-		buttonEventList = append(buttonEventList, PrimaryJustPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
-		buttonEventList = append(buttonEventList, SecondaryJustPressed)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
-		buttonEventList = append(buttonEventList, StartJustPressed)
-	}
+func (driver *keyboardDriver) DeviceId() int {
+	return driver.deviceId
+}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
-		buttonEventList = append(buttonEventList, SelectJustPressed)
-	}
+func (driver *keyboardDriver) Kind() match.DeviceKind {
+	return match.KeyboardDevice
+}
 
-	return buttonEventList
+func (driver *keyboardDriver) Poll(tick int) []match.InputEvent {
+	// keys don't need a gamepad id or a deadzone
+	pressed := driver.bindings(driver.deviceId).pressedActions(0, defaultStickDeadzone)
+	return match.ActionsToInputEvents(driver, tick, pressed, &driver.held)
 }
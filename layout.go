@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// the size everything was first drawn at, menus still draw at this size and get scaled
+const baseScreenWidth = 640
+const baseScreenHeight = 480
+
+const minScreenWidth = 320
+const minScreenHeight = 240
+
+// playfield columns are three times as tall as they are wide, like the original 160x480
+const playfieldAspect = 3
+const basePlayfieldWidth = 160
+
+// more players than this wrap onto a second row
+const playfieldsPerRow = 4
+
+// stages that lay out playfields across the whole window
+func (stage GameStage) usesPlayfieldLayout() bool {
+	switch stage {
+	case PlayerAssignment, MatchRunning, MatchPaused, MatchEnded, EditorTestPlay:
+		return true
+	}
+	return false
+}
+
+// the column for one of playerCount playfields in a width x height screen
+// up to four share a row, more wrap onto a second row, each row is centred
+func playfieldRect(playerIndex int, playerCount int, width int, height int) image.Rectangle {
+	if playerCount < 1 {
+		playerCount = 1
+	}
+
+	rows := (playerCount + playfieldsPerRow - 1) / playfieldsPerRow
+	perRow := (playerCount + rows - 1) / rows
+
+	columnWidth := width / perRow
+	if columnWidth > height/rows/playfieldAspect {
+		columnWidth = height / rows / playfieldAspect
+	}
+	columnHeight := columnWidth * playfieldAspect
+
+	row, column := playerIndex/perRow, playerIndex%perRow
+	inRow := perRow
+	if row == rows-1 {
+		inRow = playerCount - row*perRow
+	}
+
+	left := (width-columnWidth*inRow)/2 + column*columnWidth
+	top := (height-columnHeight*rows)/2 + row*columnHeight
+	return image.Rect(left, top, left+columnWidth, top+columnHeight)
+}
+
+// resize every playfield for the current screen and player count
+func (g *Game) layoutPlayfields() {
+	for playerIndex, pv := range g.playfieldViz {
+		rect := playfieldRect(playerIndex, len(g.playfieldViz), g.screenWidth, g.screenHeight)
+		pv.SetPixelSizeAndOffset(rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y)
+	}
+}
+
+func (g *Game) setScreenSize(width int, height int) {
+	if width == g.screenWidth && height == g.screenHeight {
+		return
+	}
+
+	g.screenWidth, g.screenHeight = width, height
+	g.inputDriver.touchDriver.SetScreenSize(width, height)
+	g.layoutPlayfields()
+}
+
+// f11 switches between a window and fullscreen
+func updateFullscreen() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
+}
+
+// draw a base sized screen as big as fits, centred
+func drawScaledToFit(screen *ebiten.Image, fixed *ebiten.Image) {
+	screenWidth, screenHeight := screen.Size()
+	scale := float64(screenWidth) / baseScreenWidth
+	if heightScale := float64(screenHeight) / baseScreenHeight; heightScale < scale {
+		scale = heightScale
+	}
+
+	geom := ebiten.GeoM{}
+	geom.Scale(scale, scale)
+	geom.Translate((float64(screenWidth)-baseScreenWidth*scale)/2, (float64(screenHeight)-baseScreenHeight*scale)/2)
+	screen.DrawImage(fixed, &ebiten.DrawImageOptions{GeoM: geom, Filter: ebiten.FilterLinear})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+const leaderboardRows = 10
+
+func (g *Game) updateLeaderboard(buttonPressEvents map[int][]match.GamepadEvent) {
+	for _, events := range buttonPressEvents {
+		for _, event := range events {
+			switch event {
+			case match.PrimaryJustPressed:
+				filePath, err := ratingsCSVFilePath()
+				if err == nil {
+					err = g.profileStore.ExportRatingsCSV(filePath)
+				}
+				if err != nil {
+					g.leaderboardMessage = err.Error()
+				} else {
+					g.leaderboardMessage = "Exported " + filePath
+				}
+			case match.SecondaryJustPressed:
+				g.profileStore.ratingsEnabled = !g.profileStore.ratingsEnabled
+				g.saveProfiles()
+			case match.StartJustPressed, match.SelectJustPressed:
+				g.ResetGame()
+				return
+			}
+		}
+	}
+}
+
+func (g *Game) drawLeaderboard(screen *ebiten.Image) {
+	textColor := color.RGBA{128, 128, 128, 255}
+
+	text.Draw(screen, "Leaderboard", BaseTextFont, 100, 50, textColor)
+
+	ratingsState := "Off"
+	if g.profileStore.ratingsEnabled {
+		ratingsState = "On"
+	}
+	text.Draw(screen, "Ratings: "+ratingsState, BaseTextFont, 400, 50, textColor)
+	text.Draw(screen, "A: export CSV   B: ratings on/off   Start: back", SmallTextFont, 100, 80, textColor)
+
+	for i, profile := range g.profileStore.Leaderboard() {
+		if i >= leaderboardRows {
+			break
+		}
+
+		rating := "-"
+		if profile.RatedMatches > 0 {
+			rating = fmt.Sprintf("%.0f", profile.Rating)
+		}
+
+		y := 130 + i*30
+		text.Draw(screen, fmt.Sprintf("%d.", i+1), BaseTextFont, 60, y, textColor)
+		text.Draw(screen, profile.Name, BaseTextFont, 110, y, textColor)
+		text.Draw(screen, rating, BaseTextFont, 330, y, textColor)
+		text.Draw(screen, fmt.Sprintf("%d-%d", profile.Lifetime.Wins, profile.Lifetime.Losses), BaseTextFont, 450, y, textColor)
+	}
+
+	text.Draw(screen, g.leaderboardMessage, SmallTextFont, 100, 460, textColor)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	_ "image/png"
 	"log"
 
@@ -9,16 +10,24 @@ import (
 )
 
 func main() {
+	assetDir := flag.String("assets", "", "directory of images to use instead of the built-in ones")
+	record := flag.Bool("record", false, "save a replay of every match to the replays folder in the config dir")
+	flag.Parse()
+
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
-	game, err := NewGame()
+	game, err := NewGame(*assetDir)
 
 	if err != nil {
-		log.Fatal("Could not create game")
+		log.Fatalf("Could not create game: %v", err)
 		return
 	}
+	game.recordReplays = *record
+
 	// Specify the window size as you like. Here, a doubled size is specified.
-	ebiten.SetWindowSize(640, 480)
+	ebiten.SetWindowSize(baseScreenWidth, baseScreenHeight)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowSizeLimits(minScreenWidth, minScreenHeight, -1, -1)
 	ebiten.SetWindowTitle("Dr. Breaktime")
 	// Call ebiten.RunGame to start your game loop.
 	if err := ebiten.RunGame(game); err != nil {
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var errTestsDone = errors.New("tests done")
+
+// runs the tests inside the game loop, ebiten can only draw and read pixels back once it's running
+type testGame struct {
+	m    *testing.M
+	code int
+}
+
+func (tg *testGame) Update() error {
+	tg.code = tg.m.Run()
+	return errTestsDone
+}
+
+func (tg *testGame) Draw(screen *ebiten.Image) {
+}
+
+func (tg *testGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return snapshotWidth, snapshotHeight
+}
+
+// this opens a small window while the tests run, use xvfb-run on Linux without a display
+func TestMain(m *testing.M) {
+	ebiten.SetWindowSize(snapshotWidth, snapshotHeight)
+	ebiten.SetWindowTitle("Dr. Breaktime tests")
+
+	tg := &testGame{m: m}
+	if err := ebiten.RunGame(tg); err != nil && err != errTestsDone {
+		log.Fatal(err)
+	}
+	os.Exit(tg.code)
+}
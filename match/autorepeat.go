@@ -0,0 +1,76 @@
+package match
+
+// delayed auto shift, in ticks at 60 fps
+// a held direction repeats once after the delay and then every rate ticks
+type AutoRepeatSettings struct {
+	ShiftDelay int `json:"shiftDelay"` // left and right
+	ShiftRate  int `json:"shiftRate"`
+	DropDelay  int `json:"dropDelay"` // down
+	DropRate   int `json:"dropRate"`
+}
+
+const minAutoRepeatTicks = 1
+const maxAutoRepeatTicks = 30
+
+// shift is the classic 16 frame delay and 6 frame repeat,
+// drop repeats quickly so holding down works as a soft drop
+var DefaultAutoRepeat = AutoRepeatSettings{ShiftDelay: 16, ShiftRate: 6, DropDelay: 5, DropRate: 5}
+
+// directions that repeat, the held event to watch and the press to repeat
+var autoRepeatDirections = [...]struct {
+	held    GamepadEvent
+	pressed GamepadEvent
+}{
+	{LeftPressed, LeftJustPressed},
+	{RightPressed, RightJustPressed},
+	{DownPressed, DownJustPressed},
+}
+
+type AutoRepeater struct {
+	settings  AutoRepeatSettings
+	heldTicks [len(autoRepeatDirections)]int // ticks each direction has been held, 0 when up
+}
+
+func NewAutoRepeater(settings AutoRepeatSettings) *AutoRepeater {
+	ar := &AutoRepeater{settings: settings}
+	return ar
+}
+
+// add repeat presses for held directions, a repeat looks just like a fresh press
+// call once a tick with the player's events
+func (ar *AutoRepeater) Apply(events []GamepadEvent) []GamepadEvent {
+	for i, direction := range autoRepeatDirections {
+		if !CheckControllerEventsForEvent(events, direction.held) {
+			ar.heldTicks[i] = 0
+			continue
+		}
+
+		// the first tick held is the press itself
+		if ar.heldTicks[i] > 0 && ar.isRepeatTick(i, ar.heldTicks[i]) {
+			events = append(events, direction.pressed)
+		}
+		ar.heldTicks[i]++
+	}
+
+	return events
+}
+
+func (ar *AutoRepeater) isRepeatTick(direction int, held int) bool {
+	delay, rate := ar.settings.ShiftDelay, ar.settings.ShiftRate
+	if autoRepeatDirections[direction].held == DownPressed {
+		delay, rate = ar.settings.DropDelay, ar.settings.DropRate
+	}
+
+	return held >= delay && (held-delay)%rate == 0
+}
+
+// Clamp settings from a file into range
+func (settings *AutoRepeatSettings) Clamp() {
+	for _, ticks := range []*int{&settings.ShiftDelay, &settings.ShiftRate, &settings.DropDelay, &settings.DropRate} {
+		if *ticks < minAutoRepeatTicks {
+			*ticks = minAutoRepeatTicks
+		} else if *ticks > maxAutoRepeatTicks {
+			*ticks = maxAutoRepeatTicks
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package match
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ticks a direction is held for, counting from the press, that come out with a repeat press
+func repeatTicks(ar *AutoRepeater, held GamepadEvent, pressed GamepadEvent, ticks int) []int {
+	repeats := make([]int, 0)
+	for tick := 0; tick < ticks; tick++ {
+		events := ar.Apply([]GamepadEvent{held})
+		if CheckControllerEventsForEvent(events, pressed) {
+			repeats = append(repeats, tick)
+		}
+	}
+	return repeats
+}
+
+func TestAutoRepeatDelayAndRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings AutoRepeatSettings
+		held     GamepadEvent
+		pressed  GamepadEvent
+		ticks    int
+		want     []int
+	}{
+		{"default shift", DefaultAutoRepeat, LeftPressed, LeftJustPressed, 30, []int{16, 22, 28}},
+		{"default drop", DefaultAutoRepeat, DownPressed, DownJustPressed, 16, []int{5, 10, 15}},
+		{"fast shift", AutoRepeatSettings{ShiftDelay: 2, ShiftRate: 1, DropDelay: 30, DropRate: 30}, RightPressed, RightJustPressed, 5, []int{2, 3, 4}},
+		{"shift settings don't touch drop", AutoRepeatSettings{ShiftDelay: 1, ShiftRate: 1, DropDelay: 4, DropRate: 3}, DownPressed, DownJustPressed, 11, []int{4, 7, 10}},
+		{"released before the delay", DefaultAutoRepeat, LeftPressed, LeftJustPressed, 15, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := repeatTicks(NewAutoRepeater(tt.settings), tt.held, tt.pressed, tt.ticks)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("repeated on ticks %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// letting go starts the delay over on the next press
+func TestAutoRepeatReleaseResetsDelay(t *testing.T) {
+	settings := AutoRepeatSettings{ShiftDelay: 3, ShiftRate: 2, DropDelay: 3, DropRate: 2}
+	ar := NewAutoRepeater(settings)
+
+	if got := repeatTicks(ar, LeftPressed, LeftJustPressed, 4); !reflect.DeepEqual(got, []int{3}) {
+		t.Fatalf("first hold repeated on ticks %v, want [3]", got)
+	}
+	ar.Apply([]GamepadEvent{})
+	if got := repeatTicks(ar, LeftPressed, LeftJustPressed, 6); !reflect.DeepEqual(got, []int{3, 5}) {
+		t.Errorf("second hold repeated on ticks %v, want [3 5]", got)
+	}
+}
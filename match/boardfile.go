@@ -0,0 +1,350 @@
+package match
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"example.com/drbreakboard"
+)
+
+// Board files are plain text with one line per playfield row, top row first.
+// Every space takes two characters:
+//
+//	first character:  R, Y or B for the colour, '.' for an empty space
+//	second character: '*' for a virus
+//	                  'o' for an unlinked pill half
+//	                  '^', 'v', '<', '>' for a pill half linked to the space in that direction
+//
+// An empty space is written as "..", so a red virus next to a horizontal
+// blue/yellow pill and an empty space reads "R*B>Y<..".
+// Lines starting with '#' are comments and blank lines are ignored.
+//
+// An optional "pills:" line sets the pill sequence dealt on the board as
+// colour pairs, primary half first, e.g. "pills: RB YY BR".
+
+const boardCommentPrefix = "#"
+const boardPillsPrefix = "pills:"
+const boardEmptyCell = ".."
+
+// a board and the optional pill sequence that goes with it
+type BoardFile struct {
+	Playfield *drbreakboard.PlayField
+
+	// Pills to deal in order, primary colour first
+	// empty means Pills are generated randomly
+	Pills [][2]drbreakboard.SpaceColor
+}
+
+// boardParseError reports where in a board file parsing failed
+// line and column are 1 based to match what an editor shows
+type boardParseError struct {
+	line   int
+	column int
+	msg    string
+}
+
+func (e *boardParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.line, e.column, e.msg)
+}
+
+// cell location of each parsed space, used to report linkage errors
+type boardCellPosition struct {
+	line   int
+	column int
+}
+
+func ParseBoard(r io.Reader) (*BoardFile, error) {
+	bf := &BoardFile{Pills: make([][2]drbreakboard.SpaceColor, 0)}
+	rows := make([][]drbreakboard.Space, 0)
+	positions := make([][]boardCellPosition, 0)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		// skip comments and blank lines
+		if line == "" || strings.HasPrefix(line, boardCommentPrefix) {
+			continue
+		}
+
+		if strings.HasPrefix(line, boardPillsPrefix) {
+			pills, err := parsePillSequence(line, lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			bf.Pills = append(bf.Pills, pills...)
+			continue
+		}
+
+		if len(line)%2 != 0 {
+			return nil, &boardParseError{lineNumber, len(line), "row has an odd number of characters"}
+		}
+
+		row := make([]drbreakboard.Space, 0, len(line)/2)
+		rowPositions := make([]boardCellPosition, 0, len(line)/2)
+		for i := 0; i < len(line); i += 2 {
+			space, charOffset, err := SpaceFromCell(line[i : i+2])
+			if err != nil {
+				return nil, &boardParseError{lineNumber, i + charOffset + 1, err.Error()}
+			}
+			row = append(row, space)
+			rowPositions = append(rowPositions, boardCellPosition{lineNumber, i + 1})
+		}
+
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, &boardParseError{lineNumber, 1,
+				fmt.Sprintf("row has %d spaces, expected %d", len(row), len(rows[0]))}
+		}
+
+		rows = append(rows, row)
+		positions = append(positions, rowPositions)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, &boardParseError{lineNumber + 1, 1, "board has no rows"}
+	}
+
+	// every linked half needs a partner pointing back at it
+	for y, row := range rows {
+		for x, space := range row {
+			if space.Content != drbreakboard.Pill || space.Linkage == drbreakboard.Unlinked {
+				continue
+			}
+
+			pos := positions[y][x]
+			linkedY, linkedX, err := drbreakboard.GetLinkedCoordinate(y, x, space.Linkage)
+			if err != nil || linkedY < 0 || linkedY >= len(rows) || linkedX < 0 || linkedX >= len(row) {
+				return nil, &boardParseError{pos.line, pos.column, "pill half is linked off the board"}
+			}
+
+			partner := rows[linkedY][linkedX]
+			if partner.Content != drbreakboard.Pill || partner.Linkage == drbreakboard.Unlinked {
+				return nil, &boardParseError{pos.line, pos.column, "pill half is linked to a space that is not a linked pill half"}
+			}
+
+			backY, backX, err := drbreakboard.GetLinkedCoordinate(linkedY, linkedX, partner.Linkage)
+			if err != nil || backY != y || backX != x {
+				return nil, &boardParseError{pos.line, pos.column, "pill half linkage does not match its partner"}
+			}
+		}
+	}
+
+	bf.Playfield = drbreakboard.NewPlayField(len(rows[0]), len(rows))
+	for y, row := range rows {
+		for x, space := range row {
+			if space.Content != drbreakboard.Empty {
+				bf.Playfield.ForcePutSingleSpaceIntoBoard(y, x, space)
+			}
+		}
+	}
+
+	return bf, nil
+}
+
+// parses the colour pairs on a "pills:" line
+func parsePillSequence(line string, lineNumber int) ([][2]drbreakboard.SpaceColor, error) {
+	pills := make([][2]drbreakboard.SpaceColor, 0)
+
+	column := len(boardPillsPrefix)
+	for column < len(line) {
+		// skip whitespace between pairs
+		if line[column] == ' ' || line[column] == '\t' {
+			column++
+			continue
+		}
+
+		if column+1 >= len(line) || line[column+1] == ' ' || line[column+1] == '\t' {
+			return nil, &boardParseError{lineNumber, column + 1, "pill needs two colours"}
+		}
+
+		var pill [2]drbreakboard.SpaceColor
+		for half := 0; half < 2; half++ {
+			color, err := colorFromChar(line[column+half])
+			if err != nil {
+				return nil, &boardParseError{lineNumber, column + half + 1, err.Error()}
+			}
+			pill[half] = color
+		}
+		pills = append(pills, pill)
+		column += 2
+	}
+
+	return pills, nil
+}
+
+func WriteBoard(w io.Writer, bf *BoardFile) error {
+	if bf == nil || bf.Playfield == nil {
+		return errors.New("no playfield to write")
+	}
+	playfield := bf.Playfield
+
+	bw := bufio.NewWriter(w)
+	if len(bf.Pills) > 0 {
+		bw.WriteString(boardPillsPrefix)
+		for _, pill := range bf.Pills {
+			first, err := CharFromColor(pill[0])
+			if err != nil {
+				return err
+			}
+			second, err := CharFromColor(pill[1])
+			if err != nil {
+				return err
+			}
+			bw.WriteString(" ")
+			bw.WriteString(string([]byte{first, second}))
+		}
+		bw.WriteString("\n")
+	}
+
+	for y := 0; y < playfield.GetHeight(); y++ {
+		for x := 0; x < playfield.GetWidth(); x++ {
+			space, err := playfield.GetSpaceAtCoordinate(y, x)
+			if err != nil {
+				return err
+			}
+
+			cell, err := cellFromSpace(space)
+			if err != nil {
+				return fmt.Errorf("row %d, column %d: %w", y, x, err)
+			}
+			bw.WriteString(cell)
+		}
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+func LoadBoardFile(filePath string) (*BoardFile, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	bf, err := ParseBoard(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return bf, nil
+}
+
+func SaveBoardFile(filePath string, bf *BoardFile) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteBoard(f, bf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// converts a two character cell into a space
+// on error also returns the offset in the cell of the bad character
+func SpaceFromCell(cell string) (drbreakboard.Space, int, error) {
+	if cell == boardEmptyCell {
+		return drbreakboard.Space{Content: drbreakboard.Empty}, 0, nil
+	}
+
+	space := drbreakboard.Space{}
+	color, err := colorFromChar(cell[0])
+	if err != nil {
+		return space, 0, err
+	}
+	space.Color = color
+
+	space.Content = drbreakboard.Pill
+	switch cell[1] {
+	case '*':
+		space.Content = drbreakboard.Virus
+		space.Linkage = drbreakboard.Unlinked
+	case 'o':
+		space.Linkage = drbreakboard.Unlinked
+	case '^':
+		space.Linkage = drbreakboard.Up
+	case 'v':
+		space.Linkage = drbreakboard.Down
+	case '<':
+		space.Linkage = drbreakboard.Left
+	case '>':
+		space.Linkage = drbreakboard.Right
+	default:
+		return space, 1, fmt.Errorf("unknown space type %q", cell[1])
+	}
+
+	return space, 0, nil
+}
+
+// converts a space into its two character cell
+func cellFromSpace(space drbreakboard.Space) (string, error) {
+	if space.Content == drbreakboard.Empty {
+		return boardEmptyCell, nil
+	}
+
+	colorChar, err := CharFromColor(space.Color)
+	if err != nil {
+		return "", err
+	}
+
+	var typeChar byte
+	switch space.Content {
+	case drbreakboard.Virus:
+		typeChar = '*'
+	case drbreakboard.Pill:
+		switch space.Linkage {
+		case drbreakboard.Unlinked:
+			typeChar = 'o'
+		case drbreakboard.Up:
+			typeChar = '^'
+		case drbreakboard.Down:
+			typeChar = 'v'
+		case drbreakboard.Left:
+			typeChar = '<'
+		case drbreakboard.Right:
+			typeChar = '>'
+		default:
+			return "", errors.New("pill has unknown linkage")
+		}
+	default:
+		return "", errors.New("space has unknown content")
+	}
+
+	return string([]byte{colorChar, typeChar}), nil
+}
+
+func colorFromChar(c byte) (drbreakboard.SpaceColor, error) {
+	switch c {
+	case 'R':
+		return drbreakboard.Red, nil
+	case 'Y':
+		return drbreakboard.Yellow, nil
+	case 'B':
+		return drbreakboard.Blue, nil
+	}
+	return drbreakboard.Red, fmt.Errorf("unknown colour %q", c)
+}
+
+func CharFromColor(color drbreakboard.SpaceColor) (byte, error) {
+	switch color {
+	case drbreakboard.Red:
+		return 'R', nil
+	case drbreakboard.Yellow:
+		return 'Y', nil
+	case drbreakboard.Blue:
+		return 'B', nil
+	}
+	return 0, errors.New("space has no colour")
+}
@@ -0,0 +1,91 @@
+package match
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// boards that should come back out of WriteBoard exactly as they went in
+func TestBoardRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", "........\n........\n"},
+		{"viruses", "R*Y*B*..\n........\n"},
+		{"unlinked halves", "Ro..Yo..\n......Bo\n"},
+		{"horizontal pills", "R>Y<B>B<\n........\n"},
+		{"vertical pills", "Rv..Bv..\nY^..R^..\n"},
+		{"every cell", "R*Y>B<..\nYoBv..B*\nR*R^Ro..\n"},
+		{"pills", "pills: RB YY BR\nR*......\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bf, err := ParseBoard(strings.NewReader(tt.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := WriteBoard(&out, bf); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.text {
+				t.Errorf("wrote\n%s\nwant\n%s", out.String(), tt.text)
+			}
+		})
+	}
+}
+
+// comments and blank lines are skipped and not written back
+func TestParseBoardSkipsComments(t *testing.T) {
+	bf, err := ParseBoard(strings.NewReader("# a board\n\nR*..\n\n..B*\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteBoard(&out, bf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "R*..\n..B*\n"; out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestParseBoardErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		line   int
+		column int
+	}{
+		{"no rows", "# nothing\n", 2, 1},
+		{"odd row", "R*..\nR*.\n", 2, 3},
+		{"bad colour", "R*..\n..G*\n", 2, 3},
+		{"bad type", "R*R?\n", 1, 4},
+		{"short row", "R*....\n..\n", 2, 1},
+		{"linked off the board", "R>\n", 1, 1},
+		{"linked to a virus", "....\nR>Y*\n", 2, 1},
+		{"linkage mismatch", "R>Yv\n..B^\n", 1, 1},
+		{"bad pill colour", "pills: RB YG\n", 1, 12},
+		{"half a pill", "pills: RB Y\n", 1, 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBoard(strings.NewReader(tt.text))
+			var parseErr *boardParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want a parse error", err)
+			}
+			if parseErr.line != tt.line || parseErr.column != tt.column {
+				t.Errorf("error at line %d, column %d, want line %d, column %d (%v)",
+					parseErr.line, parseErr.column, tt.line, tt.column, err)
+			}
+		})
+	}
+}
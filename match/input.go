@@ -0,0 +1,75 @@
+package match
+
+import "fmt"
+
+// what the match and menus see from a player's controller each tick
+type GamepadEvent int
+
+const (
+	GamepadConnected GamepadEvent = iota
+	GamepadDisconnected
+	PrimaryJustPressed
+	SecondaryJustPressed
+	StartJustPressed
+	SelectJustPressed
+	LeftPressed
+	RightPressed
+	DownPressed
+	LeftJustPressed
+	RightJustPressed
+	DownJustPressed
+	UpJustPressed
+)
+
+type InputAction int
+
+// things a player can do, each one can be bound to any number of inputs
+const (
+	ActionPrimary InputAction = iota
+	ActionSecondary
+	ActionStart
+	ActionSelect
+	ActionLeft
+	ActionRight
+	ActionDown
+	ActionUp
+	InputActionCount
+)
+
+// names used in the bindings file
+var InputActionNames = [...]string{"primary", "secondary", "start", "select", "left", "right", "down", "up"}
+
+// event sent the frame an action is pressed
+var actionJustPressedEvents = [...]GamepadEvent{PrimaryJustPressed, SecondaryJustPressed, StartJustPressed,
+	SelectJustPressed, LeftJustPressed, RightJustPressed, DownJustPressed, UpJustPressed}
+
+// event sent every frame an action is held, only for the movement actions
+var actionHeldEvents = map[InputAction]GamepadEvent{
+	ActionLeft:  LeftPressed,
+	ActionRight: RightPressed,
+	ActionDown:  DownPressed,
+}
+
+func ActionFromName(name string) (InputAction, error) {
+	for action, actionName := range InputActionNames {
+		if actionName == name {
+			return InputAction(action), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown action %s", name)
+}
+
+// true if targetEvent is one of the controller's events this tick
+func CheckControllerEventsForEvent(controllerEvent []GamepadEvent, targetEvent GamepadEvent) bool {
+	if controllerEvent == nil {
+		return false
+	}
+
+	for _, event := range controllerEvent {
+		if event == targetEvent {
+			return true
+		}
+	}
+
+	return false
+}
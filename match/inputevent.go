@@ -0,0 +1,121 @@
+package match
+
+type DeviceKind int
+
+const (
+	KeyboardDevice DeviceKind = iota
+	GamepadDevice
+	CPUDevice
+	ReplayDevice
+	NetworkDevice
+	TouchDevice
+)
+
+type InputState int
+
+const (
+	InputPressed  InputState = iota // went down this tick
+	InputReleased                   // came up this tick
+	InputHeld                       // still down since an earlier tick
+)
+
+// one action changing or holding on one device during one tick
+type InputEvent struct {
+	DeviceId int
+	Kind     DeviceKind
+	Tick     int
+	Action   InputAction
+	State    InputState
+}
+
+// anything that can drive a player, polled once a tick
+// keyboards and gamepads read ebiten, other sources can replay, think or listen
+type InputSource interface {
+	DeviceId() int
+	Kind() DeviceKind
+	Poll(tick int) []InputEvent
+}
+
+// turn this tick's pressed actions into events, held is last tick's and gets updated
+func ActionsToInputEvents(source InputSource, tick int, pressed [InputActionCount]bool,
+	held *[InputActionCount]bool) []InputEvent {
+	events := make([]InputEvent, 0)
+
+	for action := InputAction(0); action < InputActionCount; action++ {
+		event := InputEvent{DeviceId: source.DeviceId(), Kind: source.Kind(), Tick: tick, Action: action}
+		switch {
+		case pressed[action] && !held[action]:
+			event.State = InputPressed
+		case pressed[action]:
+			event.State = InputHeld
+		case held[action]:
+			event.State = InputReleased
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+
+	*held = pressed
+	return events
+}
+
+// the old gamepad events the menus and match understand
+// movement actions send their held event on the press tick too
+func GamepadEventsFromInput(events []InputEvent) []GamepadEvent {
+	gamepadEvents := make([]GamepadEvent, 0, len(events))
+
+	for _, event := range events {
+		if event.State == InputPressed {
+			gamepadEvents = append(gamepadEvents, actionJustPressedEvents[event.Action])
+		}
+
+		heldEvent, hasHeldEvent := actionHeldEvents[event.Action]
+		if hasHeldEvent && event.State != InputReleased {
+			gamepadEvents = append(gamepadEvents, heldEvent)
+		}
+	}
+
+	return gamepadEvents
+}
+
+// plays recorded events back as a device of its own
+// event ticks count from the first poll, so a recording can start at any time
+type ReplaySource struct {
+	deviceId  int
+	events    []InputEvent // in tick order
+	next      int
+	startTick int
+	started   bool
+}
+
+func NewReplaySource(deviceId int, events []InputEvent) *ReplaySource {
+	rs := &ReplaySource{deviceId: deviceId, events: events}
+	return rs
+}
+
+func (rs *ReplaySource) DeviceId() int {
+	return rs.deviceId
+}
+
+func (rs *ReplaySource) Kind() DeviceKind {
+	return ReplayDevice
+}
+
+func (rs *ReplaySource) Poll(tick int) []InputEvent {
+	if !rs.started {
+		rs.startTick = tick
+		rs.started = true
+	}
+
+	events := make([]InputEvent, 0)
+	for rs.next < len(rs.events) && rs.events[rs.next].Tick <= tick-rs.startTick {
+		event := rs.events[rs.next]
+		event.DeviceId = rs.deviceId
+		event.Kind = ReplayDevice
+		event.Tick = tick
+		events = append(events, event)
+		rs.next++
+	}
+	return events
+}
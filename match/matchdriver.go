@@ -1,4 +1,6 @@
-package main
+// Package match runs matches without drawing anything: the boards, pills and garbage,
+// the inputs players send them, and the board and replay files they're saved in.
+package match
 
 import (
 	"errors"
@@ -11,15 +13,24 @@ import (
 // ticks by 10 pieces dropped at 30fps
 var medTicksPerIter = [...]int{20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 10, 9, 9, 8, 8, 7, 7, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 5, 5, 5, 5, 5, 4, 4, 4, 4, 4, 3, 3, 3, 3, 3, 2, 2, 2, 2, 2}
 
-const fallTick = 7           // fall rate frames at 30 fps
-const leftRightHoldTick = 10 // ticks to wait at 60 fps for left or right hold moves
+const fallTick = 7 // fall rate frames at 30 fps
 
-const boardWidth = 8
-const boardHeight = 16
+const BoardWidth = 8
+const BoardHeight = 16
+
+const TicksPerSecond = 60
+
+// survival rises start every 10 seconds and speed up with every row to a 2 second floor
+const survivalStartRiseTicks = 10 * TicksPerSecond
+const survivalMinRiseTicks = 2 * TicksPerSecond
+const survivalRiseSpeedupTicks = 15
+const survivalVirusPoints = 100 // points per virus, multiplied by the chain length
 
 type PlayerAction int
 type GameResult int
 type DropPattern int
+type MatchMode int
+type PlayerSpeed int
 
 const (
 	Start PlayerAction = iota
@@ -42,6 +53,25 @@ const (
 	ModHalfThenRight
 )
 
+// most players in one match, two rows of four on screen
+const MaxPlayers = 8
+
+// pill speed picked with the level, scales the fall ticks
+const (
+	SpeedLow PlayerSpeed = iota
+	SpeedMed
+	SpeedHi
+	playerSpeedCount
+)
+
+var SpeedNames = [...]string{"Low", "Med", "Hi"}
+
+const (
+	VersusMode MatchMode = iota
+	// solo mode where virus rows rise from the bottom until the player tops out
+	SurvivalMode
+)
+
 type playerState struct {
 	nextPill       [2]drbreakboard.Space // 2 spaces, first is the main piece, second linked
 	activePill     [2]drbreakboard.Space // 2 spaces, first is the main piece, second linked
@@ -52,8 +82,14 @@ type playerState struct {
 	piecesDropped  int
 	currentAction  PlayerAction
 	level          int
+	speed          PlayerSpeed
 	ready          bool
 
+	// hold the last move or rotate pressed between pills and apply it when the next one spawns
+	inputBufferEnabled bool
+	bufferedInput      GamepadEvent
+	hasBufferedInput   bool
+
 	// clears from previous iterations
 	// 2D so that simultaneous clears are tracked
 	// first clear is first in the array
@@ -63,44 +99,89 @@ type playerState struct {
 	// 2D so that multiple drops are stored
 	storedGarbageDrops [][]drbreakboard.SpaceColor
 
-	// ticks since side button held move
-	sideMoveTicks int
+	// board and pill sequence to play instead of a generated board
+	// nil for a normal random board
+	presetBoard *BoardFile
+
+	// index of the next pill to deal from the preset pill sequence
+	presetPillIndex int
+
+	// survival mode tracking
+	score          int
+	survivalTicks  int // ticks survived this match
+	ticksUntilRise int
+	risesDone      int
+	pendingRises   int // rows waiting for the current pill to finish before rising
+
+	stats statsTracker
 }
 
-type matchDriver struct {
-	matchStarted   bool
-	matchEnded     bool
+type Driver struct {
+	Mode           MatchMode
+	MatchStarted   bool
+	MatchEnded     bool
 	playerStates   []*playerState
+	seedRand       rand.Source // picks each match's seed when one isn't set
+	Seed           int64       // the current match's board, pills and garbage all come from this
+	seedSet        bool        // SetSeed picked the next match's seed
 	matchRand      rand.Source
-	playerFinishes []playerFinish
-	winner         int
+	PlayerFinishes []PlayerFinish
+	Winner         int
+	MatchTicks     int          // ticks since the match started
+	events         []MatchEvent // board changes waiting to be animated
 }
 
-type playerFinish struct {
-	playerIndex int
-	result      GameResult
+type PlayerFinish struct {
+	PlayerIndex int
+	Result      GameResult
 }
 
-func NewMatchDriver() *matchDriver {
-	md := &matchDriver{}
-	md.matchRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+func NewDriver() *Driver {
+	md := &Driver{}
+	md.seedRand = rand.NewSource(time.Now().UnixNano())
 	md.playerStates = make([]*playerState, 0)
 
 	return md
 }
 
-func (md *matchDriver) AddPlayer() {
+// fix the seed the next match takes its board and pills from, so a replay plays out the same
+func (md *Driver) SetSeed(seed int64) {
+	md.Seed = seed
+	md.seedSet = true
+}
+
+func (md *Driver) SetMode(mode MatchMode) {
+	md.Mode = mode
+}
+
+func (md *Driver) AddPlayer() {
 	newPlayerState := &playerState{}
 	newPlayerState.level = 10
+	newPlayerState.speed = SpeedMed
 
 	// set up player variables
 	newPlayerState.clearedColors = make([][]drbreakboard.SpaceColor, 0)
 	newPlayerState.storedGarbageDrops = make([][]drbreakboard.SpaceColor, 0)
+	newPlayerState.stats = newStatsTracker()
 
 	md.playerStates = append(md.playerStates, newPlayerState)
 }
 
-func (md *matchDriver) ChangeLevel(playerIndex int, changeAmount int) error {
+// drop a player outside of a match, later players move down a slot
+func (md *Driver) RemovePlayer(playerIndex int) error {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return errors.New("playerindex not in range")
+	}
+
+	if md.MatchStarted && !md.MatchEnded {
+		return errors.New("can't remove a player mid match")
+	}
+
+	md.playerStates = append(md.playerStates[:playerIndex], md.playerStates[playerIndex+1:]...)
+	return nil
+}
+
+func (md *Driver) ChangeLevel(playerIndex int, changeAmount int) error {
 	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
 		return errors.New("playerindex not in range")
 	}
@@ -119,7 +200,71 @@ func (md *matchDriver) ChangeLevel(playerIndex int, changeAmount int) error {
 	return nil
 }
 
-func (md *matchDriver) GetLevel(playerIndex int) (int, error) {
+func (md *Driver) SetLevel(playerIndex int, level int) error {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return errors.New("playerindex not in range")
+	}
+
+	md.playerStates[playerIndex].level = 0
+	return md.ChangeLevel(playerIndex, level)
+}
+
+func (md *Driver) ChangeSpeed(playerIndex int, changeAmount int) error {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return errors.New("playerindex not in range")
+	}
+
+	speed := md.playerStates[playerIndex].speed + PlayerSpeed(changeAmount)
+
+	// keep speed in the valid range
+	if speed < SpeedLow {
+		speed = SpeedLow
+	}
+
+	if speed >= playerSpeedCount {
+		speed = playerSpeedCount - 1
+	}
+
+	md.playerStates[playerIndex].speed = speed
+
+	return nil
+}
+
+func (md *Driver) SetSpeed(playerIndex int, speed PlayerSpeed) error {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return errors.New("playerindex not in range")
+	}
+
+	md.playerStates[playerIndex].speed = SpeedLow
+	return md.ChangeSpeed(playerIndex, int(speed))
+}
+
+func (md *Driver) SetInputBuffer(playerIndex int, enabled bool) error {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return errors.New("playerindex not in range")
+	}
+
+	md.playerStates[playerIndex].inputBufferEnabled = enabled
+	return nil
+}
+
+func (md *Driver) GetInputBuffer(playerIndex int) (bool, error) {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return false, errors.New("playerindex not in range")
+	}
+
+	return md.playerStates[playerIndex].inputBufferEnabled, nil
+}
+
+func (md *Driver) GetSpeed(playerIndex int) (PlayerSpeed, error) {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return SpeedMed, errors.New("playerindex not in range")
+	}
+
+	return md.playerStates[playerIndex].speed, nil
+}
+
+func (md *Driver) GetLevel(playerIndex int) (int, error) {
 	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
 		return 0, errors.New("playerindex not in range")
 	}
@@ -127,7 +272,7 @@ func (md *matchDriver) GetLevel(playerIndex int) (int, error) {
 	return md.playerStates[playerIndex].level, nil
 }
 
-func (md *matchDriver) SetPlayerReady(playerIndex int, ready bool) error {
+func (md *Driver) SetPlayerReady(playerIndex int, ready bool) error {
 	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
 		return errors.New("playerindex not in range")
 	}
@@ -137,7 +282,7 @@ func (md *matchDriver) SetPlayerReady(playerIndex int, ready bool) error {
 	return nil
 }
 
-func (md *matchDriver) GetPlayerReady(playerIndex int) (bool, error) {
+func (md *Driver) GetPlayerReady(playerIndex int) (bool, error) {
 	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
 		return false, errors.New("playerindex not in range")
 	}
@@ -145,7 +290,23 @@ func (md *matchDriver) GetPlayerReady(playerIndex int) (bool, error) {
 	return md.playerStates[playerIndex].ready, nil
 }
 
-func (md *matchDriver) GetViriiRemaining(playerIndex int) (int, error) {
+// play the given board and pill sequence instead of a generated one
+// pass nil to go back to generated boards
+func (md *Driver) SetPlayerBoard(playerIndex int, board *BoardFile) error {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return errors.New("playerindex not in range")
+	}
+
+	if board != nil && (board.Playfield.GetWidth() != BoardWidth || board.Playfield.GetHeight() != BoardHeight) {
+		return errors.New("preset board is not the playfield size")
+	}
+
+	md.playerStates[playerIndex].presetBoard = board
+
+	return nil
+}
+
+func (md *Driver) GetViriiRemaining(playerIndex int) (int, error) {
 	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
 		return 0, errors.New("playerindex not in range")
 	}
@@ -166,7 +327,7 @@ func (md *matchDriver) GetViriiRemaining(playerIndex int) (int, error) {
 	return viriiRemaining, nil
 }
 
-func (md *matchDriver) GetIsDropInbound(playerIndex int) (bool, error) {
+func (md *Driver) GetIsDropInbound(playerIndex int) (bool, error) {
 	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
 		return false, errors.New("playerindex not in range")
 	}
@@ -174,13 +335,86 @@ func (md *matchDriver) GetIsDropInbound(playerIndex int) (bool, error) {
 	return len(md.playerStates[playerIndex].storedGarbageDrops) > 0, nil
 }
 
-func (md *matchDriver) StartMatch() {
-	if md.matchStarted && !md.matchEnded {
+func (md *Driver) GetScore(playerIndex int) (int, error) {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return 0, errors.New("playerindex not in range")
+	}
+
+	return md.playerStates[playerIndex].score, nil
+}
+
+// returns time survived and time until the next virus row rises
+func (md *Driver) GetSurvivalTimers(playerIndex int) (time.Duration, time.Duration, error) {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return 0, 0, errors.New("playerindex not in range")
+	}
+
+	ps := md.playerStates[playerIndex]
+	return TicksToDuration(ps.survivalTicks), TicksToDuration(ps.ticksUntilRise), nil
+}
+
+// stats for the player's current or most recent match
+func (md *Driver) GetPlayerStats(playerIndex int) (PlayerStats, error) {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return PlayerStats{}, errors.New("playerindex not in range")
+	}
+
+	ps := md.playerStates[playerIndex]
+	return ps.stats.toPlayerStats(), nil
+}
+
+// placement of every player in the finished match, 1 is first
+// players who cleared come first, players still on the board when it ended
+// tie behind them and players who topped out place in reverse order of topping out
+func (md *Driver) GetPlacements() []int {
+	placements := make([]int, len(md.playerStates))
+	finished := make(map[int]bool)
+	next := 1
+
+	for _, finish := range md.PlayerFinishes {
+		finished[finish.PlayerIndex] = true
+		if finish.Result == Cleared {
+			placements[finish.PlayerIndex] = next
+			next++
+		}
+	}
+
+	stillPlaying := 0
+	for index := range md.playerStates {
+		if !finished[index] {
+			placements[index] = next
+			stillPlaying++
+		}
+	}
+	next += stillPlaying
+
+	for i := len(md.PlayerFinishes) - 1; i >= 0; i-- {
+		finish := md.PlayerFinishes[i]
+		if finish.Result == Filled {
+			placements[finish.PlayerIndex] = next
+			next++
+		}
+	}
+
+	return placements
+}
+
+func (md *Driver) StartMatch() {
+	if md.MatchStarted && !md.MatchEnded {
 		// match already started or has not ended, return
 		return
 	}
 
-	md.playerFinishes = make([]playerFinish, 0)
+	md.PlayerFinishes = make([]PlayerFinish, 0)
+	md.MatchTicks = 0
+	md.events = nil
+
+	// everything random in the match comes from its seed, so the seed and the inputs replay it
+	if !md.seedSet {
+		md.Seed = md.seedRand.Int63()
+	}
+	md.seedSet = false
+	md.matchRand = rand.New(rand.NewSource(md.Seed))
 
 	// pick the seed for the match to sync random number generators
 	// ensures same board, pills, etc.
@@ -189,17 +423,31 @@ func (md *matchDriver) StartMatch() {
 	// set up each playerstate for a new match
 	for _, playerState := range md.playerStates {
 		// initialize player board
-		playerState.playfield = drbreakboard.NewPlayField(boardWidth, boardHeight)
-		populateBoardViruses(playerState.playfield, playerState.level, matchSeed)
+		if playerState.presetBoard != nil {
+			playerState.playfield = copyPlayField(playerState.presetBoard.Playfield)
+		} else {
+			playerState.playfield = drbreakboard.NewPlayField(BoardWidth, BoardHeight)
+			populateBoardViruses(playerState.playfield, playerState.level, matchSeed)
+		}
 
 		playerState.pillRand = rand.New(rand.NewSource(matchSeed))
-		playerState.nextPill[0], playerState.nextPill[1] = generatePill(playerState.pillRand)
+		playerState.presetPillIndex = 0
+		playerState.nextPill[0], playerState.nextPill[1] = md.dealPill(playerState)
+
+		playerState.score = 0
+		playerState.survivalTicks = 0
+		playerState.ticksUntilRise = survivalStartRiseTicks
+		playerState.risesDone = 0
+		playerState.pendingRises = 0
+
+		playerState.stats = newStatsTracker()
+		playerState.hasBufferedInput = false
 	}
 
-	md.matchStarted = true
+	md.MatchStarted = true
 }
 
-func (md *matchDriver) ResetAndStartMatch() {
+func (md *Driver) ResetAndStartMatch() {
 	// set up each playerstate for a new match
 	for _, playerState := range md.playerStates {
 		// reset the states
@@ -213,22 +461,48 @@ func (md *matchDriver) ResetAndStartMatch() {
 		playerState.storedGarbageDrops = [][]drbreakboard.SpaceColor{}
 	}
 
-	md.matchStarted = false
-	md.matchEnded = false
-	md.playerFinishes = make([]playerFinish, 0)
+	md.MatchStarted = false
+	md.MatchEnded = false
+	md.PlayerFinishes = make([]PlayerFinish, 0)
 
 	md.StartMatch()
 }
 
-func (md *matchDriver) ApplyInputs(playerInputs map[int][]GamepadEvent) {
+// count the moves and rotations players pressed for the inputs per pill stat
+// call with the device or replay events, before auto repeat adds its presses
+func (md *Driver) CountInputs(playerInputs map[int][]GamepadEvent) {
+	for index, ps := range md.playerStates {
+		// presses between pills only do something when they're buffered
+		if ps.currentAction != PlacingPill && (!ps.inputBufferEnabled || ps.currentAction == FilledBoard) {
+			continue
+		}
+
+		for _, input := range playerInputs[index] {
+			switch input {
+			case LeftJustPressed, RightJustPressed, PrimaryJustPressed, SecondaryJustPressed:
+				ps.stats.pillInputs++
+			}
+		}
+	}
+}
+
+func (md *Driver) ApplyInputs(playerInputs map[int][]GamepadEvent) {
 	for index, ps := range md.playerStates {
 		if ps.currentAction != PlacingPill {
 			// if not placing pill, input means nothing
-			// reset side move ticks
-			ps.sideMoveTicks = 0
+			// unless it's buffered for the next pill
+			if ps.inputBufferEnabled {
+				md.bufferInputs(ps, playerInputs[index])
+			}
 			continue
 		}
 
+		// first tick of a new pill, play the buffered input before anything else
+		if ps.hasBufferedInput {
+			md.applyPillInput(index, ps, ps.bufferedInput)
+			ps.hasBufferedInput = false
+		}
+
 		playerInput, hasInput := playerInputs[index]
 		if !hasInput {
 			// no input for that player, skip
@@ -236,48 +510,37 @@ func (md *matchDriver) ApplyInputs(playerInputs map[int][]GamepadEvent) {
 		}
 
 		for _, input := range playerInput {
-			switch input {
-			case LeftJustPressed:
-				md.moveLeftIfPossible(index, ps)
-			case RightJustPressed:
-				md.moveRightIfPossible(index, ps)
-			case PrimaryJustPressed:
-				md.rotateIfPossible(index, ps, true)
-			case SecondaryJustPressed:
-				md.rotateIfPossible(index, ps, false)
-			}
+			md.applyPillInput(index, ps, input)
 		}
+	}
+}
 
-		// check whether side move direction is held
-		// move if held for number of ticks
-		sideMoveHeld := false
-		for _, input := range playerInput {
-			switch input {
-			case LeftPressed:
-				sideMoveHeld = true
-				ps.sideMoveTicks += 1
-				if ps.sideMoveTicks == leftRightHoldTick {
-					md.moveLeftIfPossible(index, ps)
-					ps.sideMoveTicks = 0
-				}
-			case RightPressed:
-				sideMoveHeld = true
-				ps.sideMoveTicks += 1
-				if ps.sideMoveTicks == leftRightHoldTick {
-					md.moveRightIfPossible(index, ps)
-					ps.sideMoveTicks = 0
-				}
-			}
-		}
+// move or rotate the active pill for one input
+func (md *Driver) applyPillInput(index int, ps *playerState, input GamepadEvent) {
+	switch input {
+	case LeftJustPressed:
+		md.moveLeftIfPossible(index, ps)
+	case RightJustPressed:
+		md.moveRightIfPossible(index, ps)
+	case PrimaryJustPressed:
+		md.rotateIfPossible(index, ps, true)
+	case SecondaryJustPressed:
+		md.rotateIfPossible(index, ps, false)
+	}
+}
 
-		// no side move, set side move ticks to zero
-		if !sideMoveHeld {
-			ps.sideMoveTicks = 0
+// remember the latest move or rotate, it replaces anything buffered before it
+func (md *Driver) bufferInputs(ps *playerState, playerInput []GamepadEvent) {
+	for _, input := range playerInput {
+		switch input {
+		case LeftJustPressed, RightJustPressed, PrimaryJustPressed, SecondaryJustPressed:
+			ps.bufferedInput = input
+			ps.hasBufferedInput = true
 		}
 	}
 }
 
-func (md *matchDriver) rotateIfPossible(index int, ps *playerState, clockwise bool) {
+func (md *Driver) rotateIfPossible(index int, ps *playerState, clockwise bool) {
 	if ps.currentAction != PlacingPill {
 		// if not placing pill, there's no pill to rotate
 		return
@@ -292,7 +555,7 @@ func (md *matchDriver) rotateIfPossible(index int, ps *playerState, clockwise bo
 	}
 }
 
-func (md *matchDriver) rotateHorToVert(index int, ps *playerState, clockwise bool) {
+func (md *Driver) rotateHorToVert(index int, ps *playerState, clockwise bool) {
 	// first, see if spot above primary is open and go there
 	aboveRoot, err := md.GetPlayfield(index).GetSpaceAtCoordinate(ps.pillPosition[0]-1, ps.pillPosition[1])
 	if err == nil && aboveRoot.Content == drbreakboard.Empty {
@@ -364,7 +627,7 @@ func (md *matchDriver) rotateHorToVert(index int, ps *playerState, clockwise boo
 	// no valid spots return having done nothing
 }
 
-func (md *matchDriver) rotateVertToHor(index int, ps *playerState, clockwise bool) {
+func (md *Driver) rotateVertToHor(index int, ps *playerState, clockwise bool) {
 	// first, see if spot to the left is open and move the piece there
 	rightOfPiece, err := md.GetPlayfield(index).GetSpaceAtCoordinate(ps.pillPosition[0], ps.pillPosition[1]+1)
 	if err == nil && rightOfPiece.Content == drbreakboard.Empty {
@@ -399,10 +662,7 @@ func (md *matchDriver) rotateVertToHor(index int, ps *playerState, clockwise boo
 	}
 }
 
-func (md *matchDriver) moveLeftIfPossible(index int, ps *playerState) {
-	// reset side move ticks for held moves
-	ps.sideMoveTicks = 0
-
+func (md *Driver) moveLeftIfPossible(index int, ps *playerState) {
 	leftOfPiece, err := md.GetPlayfield(index).GetSpaceAtCoordinate(ps.pillPosition[0], ps.pillPosition[1]-1)
 
 	// out of bounds
@@ -431,10 +691,7 @@ func (md *matchDriver) moveLeftIfPossible(index int, ps *playerState) {
 	ps.pillPosition[1] -= 1
 }
 
-func (md *matchDriver) moveRightIfPossible(index int, ps *playerState) {
-	// reset side move ticks for held moves
-	ps.sideMoveTicks = 0
-
+func (md *Driver) moveRightIfPossible(index int, ps *playerState) {
 	// same check for piece above if piece oriented vertically
 	// represented with an up linkage on the primary space
 	if ps.activePill[0].Linkage == drbreakboard.Up {
@@ -477,7 +734,9 @@ func (md *matchDriver) moveRightIfPossible(index int, ps *playerState) {
 	ps.pillPosition[1] += 1
 }
 
-func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
+func (md *Driver) ApplyTick(playerInputs map[int][]GamepadEvent) {
+	md.MatchTicks++
+
 	for playerIndex, ps := range md.playerStates {
 		tickRateIndex := ps.piecesDropped / 10
 		if tickRateIndex >= len(medTicksPerIter) {
@@ -485,43 +744,43 @@ func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
 		}
 		iterTicks := medTicksPerIter[tickRateIndex] * 2 //convert to 60 ticks per sec
 
+		// low is half again as slow as med, hi is twice as fast
+		switch ps.speed {
+		case SpeedLow:
+			iterTicks = iterTicks * 3 / 2
+		case SpeedHi:
+			iterTicks = iterTicks / 2
+		}
+
+		if md.Mode == SurvivalMode && ps.currentAction != FilledBoard {
+			md.tickSurvivalTimer(ps)
+		}
+
 		switch ps.currentAction {
 		case Start, ReadyForNext:
-			// check that there's a place to put the piece
-			left, _ := ps.playfield.GetSpaceAtCoordinate(0, 3)
-			right, _ := ps.playfield.GetSpaceAtCoordinate(0, 4)
-			if left.Content != drbreakboard.Empty || right.Content != drbreakboard.Empty {
+			// check that there's a place to put the piece, before a rise can push it off the top
+			if spawnBlocked(ps.playfield) {
 				// board is full, you lose
-				md.playerFinishes = append(md.playerFinishes, playerFinish{playerIndex, Filled})
-				ps.currentAction = FilledBoard
-
-				// get number of filled boards
-				var filledBoards = 0
-				for index := range md.playerStates {
-					if md.playerStates[index].currentAction == FilledBoard {
-						filledBoards += 1
-					}
-				}
+				md.topOut(playerIndex)
+				break
+			}
 
-				if filledBoards >= len(md.playerStates)-1 {
-					// everyone filled their board except one, game is over
-					md.matchEnded = true
+			if ps.pendingRises > 0 {
+				// raise the board between pills so the active pill never collides
+				md.riseVirusRow(ps)
+				ps.pendingRises--
 
-					// get the winner
-					for index := range md.playerStates {
-						if md.playerStates[index].currentAction != FilledBoard {
-							md.winner = index
-						}
-					}
-				}
+				// the new row can line up with what's above it
+				ps.currentAction = Evaluate
 			} else {
 				// put the piece into play
 				ps.activePill = ps.nextPill
-				ps.nextPill[0], ps.nextPill[1] = generatePill(ps.pillRand)
+				ps.nextPill[0], ps.nextPill[1] = md.dealPill(ps)
 
 				// put the pill in row 0, middle column
 				ps.pillPosition[0] = 0
 				ps.pillPosition[1] = 3
+				ps.stats.pillSpawnTick = md.MatchTicks
 
 				ps.currentAction = PlacingPill
 			}
@@ -544,47 +803,13 @@ func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
 				ps.currentAction = ReadyForNext
 			}
 		case PlacingPill:
-			dropIterTicks := iterTicks
-			const controllerHoldIterTicks = 5 // tenth of a second
-
-			// if down is held, lower the tick wait threshold
-			playerInput, hasInput := playerInputs[playerIndex]
-			if hasInput {
-				for _, command := range playerInput {
-					if command == DownPressed && iterTicks > controllerHoldIterTicks {
-						dropIterTicks = controllerHoldIterTicks
-					}
-				}
-			}
+			// a down press drops the pill a row right away
+			// holding down auto repeats the press for a soft drop
+			downPressed := CheckControllerEventsForEvent(playerInputs[playerIndex], DownJustPressed)
 
-			if ps.ticksSinceIter >= dropIterTicks {
+			if downPressed || ps.ticksSinceIter >= iterTicks {
 				// time to drop the pill
-				dropBlocked := false
-				space, err := ps.playfield.GetSpaceAtCoordinate(ps.pillPosition[0]+1, ps.pillPosition[1])
-				if err != nil {
-					// bounds check failed due to bottom
-					dropBlocked = true
-				}
-
-				if space.Content != drbreakboard.Empty {
-					// there's a thing in the space
-					dropBlocked = true
-				}
-
-				// check the linked spot
-				linkedY, linkedX, _ := drbreakboard.GetLinkedCoordinate(ps.pillPosition[0], ps.pillPosition[1], ps.activePill[0].Linkage)
-				space, err = ps.playfield.GetSpaceAtCoordinate(linkedY+1, linkedX)
-				if err != nil {
-					// bounds check failed due to bottom
-					dropBlocked = true
-				}
-
-				if space.Content != drbreakboard.Empty {
-					// there's a thing in the space
-					dropBlocked = true
-				}
-
-				if dropBlocked {
+				if isPillDropBlocked(ps, ps.pillPosition[0], ps.pillPosition[1]) {
 					// something under the piece, stop the drop
 					ps.playfield.PutTwoLinkedSpacesAtCoordinate(ps.pillPosition[0], ps.pillPosition[1],
 						ps.activePill[0], ps.activePill[1])
@@ -594,6 +819,8 @@ func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
 
 					// add one to pills dropped
 					ps.piecesDropped += 1
+					ps.stats.pillsPlaced++
+					ps.stats.placementTicks += md.MatchTicks - ps.stats.pillSpawnTick
 
 					// set state to evaluate to check pill effect
 					ps.currentAction = Evaluate
@@ -601,8 +828,8 @@ func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
 					// execute evaluation immediately
 					md.evaluateAndIterateBoard(ps, playerIndex, true)
 					if ps.currentAction == VirusesCleared {
-						md.matchEnded = true
-						md.winner = playerIndex
+						md.MatchEnded = true
+						md.Winner = playerIndex
 					}
 				} else {
 					// not blocked, drop the pill
@@ -620,8 +847,8 @@ func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
 
 			// check if this was a board clear
 			if ps.currentAction == VirusesCleared {
-				md.matchEnded = true
-				md.winner = playerIndex
+				md.MatchEnded = true
+				md.Winner = playerIndex
 			}
 		case VirusesCleared:
 			// no-op
@@ -629,7 +856,44 @@ func (md *matchDriver) ApplyTick(playerInputs map[int][]GamepadEvent) {
 	}
 }
 
-func (md *matchDriver) evaluateAndIterateBoard(ps *playerState, playerIndex int, ignoreTicks bool) {
+// true if the active pill at y, x can't fall another row
+func isPillDropBlocked(ps *playerState, y int, x int) bool {
+	space, err := ps.playfield.GetSpaceAtCoordinate(y+1, x)
+	if err != nil || space.Content != drbreakboard.Empty {
+		// the bottom or a thing in the space
+		return true
+	}
+
+	// check the linked spot
+	linkedY, linkedX, _ := drbreakboard.GetLinkedCoordinate(y, x, ps.activePill[0].Linkage)
+	space, err = ps.playfield.GetSpaceAtCoordinate(linkedY+1, linkedX)
+	if err != nil || space.Content != drbreakboard.Empty {
+		return true
+	}
+
+	return false
+}
+
+// where the active pill would land if it dropped straight down
+// false if there's no pill in play
+func (md *Driver) GetGhostPillLocation(playerIndex int) ([2]int, bool) {
+	if playerIndex < 0 || playerIndex >= len(md.playerStates) {
+		return [2]int{}, false
+	}
+
+	ps := md.playerStates[playerIndex]
+	if ps.currentAction != PlacingPill || ps.activePill[0].Content == drbreakboard.Empty {
+		return [2]int{}, false
+	}
+
+	y, x := ps.pillPosition[0], ps.pillPosition[1]
+	for !isPillDropBlocked(ps, y, x) {
+		y++
+	}
+	return [2]int{y, x}, true
+}
+
+func (md *Driver) evaluateAndIterateBoard(ps *playerState, playerIndex int, ignoreTicks bool) {
 	if !ignoreTicks && ps.ticksSinceIter < fallTick {
 		// not time for next eval add to tick count
 		ps.ticksSinceIter++
@@ -637,8 +901,10 @@ func (md *matchDriver) evaluateAndIterateBoard(ps *playerState, playerIndex int,
 	}
 
 	_, nextIteration, clears := ps.playfield.EvaluateBoardIteration()
+	virusesBefore := ps.playfield.GetVirusCount()
 	if nextIteration == drbreakboard.NoAction {
 		// board has no falls or clears
+		ps.stats.endChain()
 
 		// check if a combo occcured to set up drops
 		clearCount := 0
@@ -666,16 +932,25 @@ func (md *matchDriver) evaluateAndIterateBoard(ps *playerState, playerIndex int,
 		}
 	} else {
 		// board has activity, iterate and evaluate again
+		before := copyPlayField(ps.playfield)
 		err := ps.playfield.IterateBoard()
 		if err != nil {
 			panic("iterate went wrong")
 		}
+		md.emitIteration(playerIndex, before, ps.playfield, nextIteration == drbreakboard.Clear)
 
 		if nextIteration == drbreakboard.Clear {
-			// after clear, see if we still have viruses
-			if ps.playfield.GetVirusCount() == 0 {
+			virusesCleared := virusesBefore - ps.playfield.GetVirusCount()
+			ps.stats.addClear(virusesCleared, md.MatchTicks)
+
+			if md.Mode == SurvivalMode {
+				// survival never runs out of viruses, just score the clear
+				ps.clearedColors = append(ps.clearedColors, clears)
+				ps.score += virusesCleared * survivalVirusPoints * len(ps.clearedColors)
+			} else if ps.playfield.GetVirusCount() == 0 {
+				// no viruses left after the clear
 				// match is over, make the state match
-				md.playerFinishes = append(md.playerFinishes, playerFinish{playerIndex, Cleared})
+				md.PlayerFinishes = append(md.PlayerFinishes, PlayerFinish{playerIndex, Cleared})
 				ps.currentAction = VirusesCleared
 			} else {
 				// add cleared virii to tracking
@@ -687,7 +962,66 @@ func (md *matchDriver) evaluateAndIterateBoard(ps *playerState, playerIndex int,
 	ps.ticksSinceIter = 0
 }
 
-func (md *matchDriver) sendGarbageToOtherPlayers(playerIndex int, clears [][]drbreakboard.SpaceColor) {
+// count down to the next virus row, speeding up with every row risen
+func (md *Driver) tickSurvivalTimer(ps *playerState) {
+	ps.survivalTicks++
+	ps.ticksUntilRise--
+
+	if ps.ticksUntilRise <= 0 {
+		ps.pendingRises++
+		ps.risesDone++
+
+		ps.ticksUntilRise = survivalStartRiseTicks - ps.risesDone*survivalRiseSpeedupTicks
+		if ps.ticksUntilRise < survivalMinRiseTicks {
+			ps.ticksUntilRise = survivalMinRiseTicks
+		}
+	}
+}
+
+// the player's board is full, they're out
+func (md *Driver) topOut(playerIndex int) {
+	md.PlayerFinishes = append(md.PlayerFinishes, PlayerFinish{playerIndex, Filled})
+	md.playerStates[playerIndex].currentAction = FilledBoard
+	md.playerStates[playerIndex].stats.topOutRow = highestOccupiedRow(md.playerStates[playerIndex].playfield)
+	md.emit(MatchEvent{PlayerIndex: playerIndex, Kind: TopOutMatchEvent})
+
+	// get number of filled boards
+	var filledBoards = 0
+	for index := range md.playerStates {
+		if md.playerStates[index].currentAction == FilledBoard {
+			filledBoards += 1
+		}
+	}
+
+	if filledBoards >= len(md.playerStates)-1 {
+		// everyone filled their board except one, game is over
+		md.MatchEnded = true
+
+		// get the winner
+		for index := range md.playerStates {
+			if md.playerStates[index].currentAction != FilledBoard {
+				md.Winner = index
+			}
+		}
+	}
+}
+
+// push a new row of viruses in from the bottom of the board
+func (md *Driver) riseVirusRow(ps *playerState) {
+	row := generateVirusRow(ps.playfield, rand.New(md.matchRand))
+
+	// the row is made at the board's width, so it always fits
+	_ = insertRowAtBottom(ps.playfield, row)
+}
+
+// the pill spawn columns in the top row are filled, so the next pill has nowhere to go
+func spawnBlocked(playfield *drbreakboard.PlayField) bool {
+	left, _ := playfield.GetSpaceAtCoordinate(0, 3)
+	right, _ := playfield.GetSpaceAtCoordinate(0, 4)
+	return left.Content != drbreakboard.Empty || right.Content != drbreakboard.Empty
+}
+
+func (md *Driver) sendGarbageToOtherPlayers(playerIndex int, clears [][]drbreakboard.SpaceColor) {
 	// get total number of players still in the game
 	numLivePlayers := 0
 	for _, ps := range md.playerStates {
@@ -776,7 +1110,7 @@ func (md *matchDriver) sendGarbageToOtherPlayers(playerIndex int, clears [][]drb
 // params are the dropper player index, the pattern to drop, and previous victims
 // previous victims are used for directions that may overlap with previous drops
 // returns victim or error if no valid unvictimized target found
-func (md *matchDriver) applyDrops(dropperIndex int, clears []drbreakboard.SpaceColor,
+func (md *Driver) applyDrops(dropperIndex int, clears []drbreakboard.SpaceColor,
 	direction DropPattern, prevDropVictims map[int]bool) (int, error) {
 	numTotalPlayers := len(md.playerStates)
 	switch direction {
@@ -797,7 +1131,7 @@ func (md *matchDriver) applyDrops(dropperIndex int, clears []drbreakboard.SpaceC
 			// add to this player's drops if player is still alive and wasn't already targeted
 			if !exists && md.playerStates[targetIndex].currentAction != VirusesCleared &&
 				md.playerStates[targetIndex].currentAction != FilledBoard {
-				md.playerStates[targetIndex].storedGarbageDrops = append(md.playerStates[targetIndex].storedGarbageDrops, clears)
+				md.giveDrops(dropperIndex, targetIndex, clears)
 				return targetIndex, nil
 			}
 
@@ -825,7 +1159,7 @@ func (md *matchDriver) applyDrops(dropperIndex int, clears []drbreakboard.SpaceC
 			// add to this player's drops if player is still alive
 			if !exists && md.playerStates[targetIndex].currentAction != VirusesCleared &&
 				md.playerStates[targetIndex].currentAction != FilledBoard {
-				md.playerStates[targetIndex].storedGarbageDrops = append(md.playerStates[targetIndex].storedGarbageDrops, clears)
+				md.giveDrops(dropperIndex, targetIndex, clears)
 				return targetIndex, nil
 			}
 
@@ -840,27 +1174,18 @@ func (md *matchDriver) applyDrops(dropperIndex int, clears []drbreakboard.SpaceC
 		return -1, errors.New("no valid target found")
 	case ModHalfThenRight:
 		// find the start index by adding half the num players to dropper index
-		targetIndex := dropperIndex + numTotalPlayers/2
-		if targetIndex >= numTotalPlayers {
-			targetIndex = 0
-		}
-
-		// set the last possible index to the target index minus 1
-		// add num players if it falls below zero
-		lastIndex := targetIndex - 1
-		if lastIndex < 0 {
-			lastIndex += numTotalPlayers
-		}
+		// wrap around so the player across the ring is first, whatever the count
+		targetIndex := (dropperIndex + numTotalPlayers/2) % numTotalPlayers
 
 		// go right until the target is found
-		// stop if the dropper is reached
-		for targetIndex != lastIndex {
+		// every player is checked once, the dropper is skipped
+		for checked := 0; checked < numTotalPlayers; checked++ {
 			_, exists := prevDropVictims[targetIndex]
 			// add to this player's drops if player is still alive, has not been dropped on, and is not the player
 			if !exists && targetIndex != dropperIndex &&
 				md.playerStates[targetIndex].currentAction != VirusesCleared &&
 				md.playerStates[targetIndex].currentAction != FilledBoard {
-				md.playerStates[targetIndex].storedGarbageDrops = append(md.playerStates[targetIndex].storedGarbageDrops, clears)
+				md.giveDrops(dropperIndex, targetIndex, clears)
 				return targetIndex, nil
 			}
 
@@ -878,7 +1203,16 @@ func (md *matchDriver) applyDrops(dropperIndex int, clears []drbreakboard.SpaceC
 	return -1, errors.New("unrecognized direction")
 }
 
-func (md *matchDriver) insertDropToBoard(playerIndex int, drop []drbreakboard.SpaceColor) error {
+// queue drops on the target and track them for both players' stats
+func (md *Driver) giveDrops(dropperIndex int, targetIndex int, clears []drbreakboard.SpaceColor) {
+	target := md.playerStates[targetIndex]
+	target.storedGarbageDrops = append(target.storedGarbageDrops, clears)
+
+	md.playerStates[dropperIndex].stats.garbageSent[targetIndex] += len(clears)
+	target.stats.garbageReceived[dropperIndex] += len(clears)
+}
+
+func (md *Driver) insertDropToBoard(playerIndex int, drop []drbreakboard.SpaceColor) error {
 	if len(drop) < 2 {
 		return errors.New("not enough pieces in drop")
 	}
@@ -886,7 +1220,7 @@ func (md *matchDriver) insertDropToBoard(playerIndex int, drop []drbreakboard.Sp
 	pf := md.playerStates[playerIndex].playfield
 
 	// get the first drop col index
-	startIndex := int(md.matchRand.Int63() % boardWidth)
+	startIndex := int(md.matchRand.Int63() % BoardWidth)
 
 	// only do up to 4 for now
 	// drop every 2 cols
@@ -895,23 +1229,40 @@ func (md *matchDriver) insertDropToBoard(playerIndex int, drop []drbreakboard.Sp
 		drbreakboard.Space{Content: drbreakboard.Pill, Linkage: drbreakboard.Unlinked, Color: drop[0]})
 
 	if len(drop) >= 3 {
-		pf.ForcePutSingleSpaceIntoBoard(0, (startIndex+2)%boardWidth,
+		pf.ForcePutSingleSpaceIntoBoard(0, (startIndex+2)%BoardWidth,
 			drbreakboard.Space{Content: drbreakboard.Pill, Linkage: drbreakboard.Unlinked, Color: drop[2]})
 	}
 
-	pf.ForcePutSingleSpaceIntoBoard(0, (startIndex+4)%boardWidth,
+	pf.ForcePutSingleSpaceIntoBoard(0, (startIndex+4)%BoardWidth,
 		drbreakboard.Space{Content: drbreakboard.Pill, Linkage: drbreakboard.Unlinked, Color: drop[1]})
 
 	if len(drop) >= 4 {
-		pf.ForcePutSingleSpaceIntoBoard(0, (startIndex+6)%boardWidth,
+		pf.ForcePutSingleSpaceIntoBoard(0, (startIndex+6)%BoardWidth,
 			drbreakboard.Space{Content: drbreakboard.Pill, Linkage: drbreakboard.Unlinked, Color: drop[2]})
 	}
 
+	// let the new pieces be seen dropping in
+	columns := []int{0, 4}
+	if len(drop) >= 3 {
+		columns = append(columns, 2)
+	}
+	if len(drop) >= 4 {
+		columns = append(columns, 6)
+	}
+
+	event := MatchEvent{PlayerIndex: playerIndex, Kind: GarbageMatchEvent, Ticks: fallTick + 1}
+	for _, column := range columns {
+		x := (startIndex + column) % BoardWidth
+		space, _ := pf.GetSpaceAtCoordinate(0, x)
+		event.Spaces = append(event.Spaces, boardSpace{0, x, space})
+	}
+	md.emit(event)
+
 	return nil
 }
 
-func (md *matchDriver) GetPlayfield(playerIndex int) *drbreakboard.PlayField {
-	if !md.matchStarted {
+func (md *Driver) GetPlayfield(playerIndex int) *drbreakboard.PlayField {
+	if !md.MatchStarted {
 		return nil
 	}
 
@@ -922,18 +1273,120 @@ func (md *matchDriver) GetPlayfield(playerIndex int) *drbreakboard.PlayField {
 	return md.playerStates[playerIndex].playfield
 }
 
-func (md *matchDriver) GetActivePill(playerIndex int) [2]drbreakboard.Space {
+func (md *Driver) GetActivePill(playerIndex int) [2]drbreakboard.Space {
 	return md.playerStates[playerIndex].activePill
 }
 
-func (md *matchDriver) GetNextPill(playerIndex int) [2]drbreakboard.Space {
+func (md *Driver) GetNextPill(playerIndex int) [2]drbreakboard.Space {
 	return md.playerStates[playerIndex].nextPill
 }
 
-func (md *matchDriver) GetActivePillLocation(playerIndex int) [2]int {
+func (md *Driver) GetActivePillLocation(playerIndex int) [2]int {
 	return md.playerStates[playerIndex].pillPosition
 }
 
+// deal the next pill from the preset sequence if there is one
+// the sequence repeats once it runs out
+func (md *Driver) dealPill(ps *playerState) (drbreakboard.Space, drbreakboard.Space) {
+	if ps.presetBoard == nil || len(ps.presetBoard.Pills) == 0 {
+		return generatePill(ps.pillRand)
+	}
+
+	pill := ps.presetBoard.Pills[ps.presetPillIndex%len(ps.presetBoard.Pills)]
+	ps.presetPillIndex++
+
+	a, b, _ := drbreakboard.MakeLinkedPillSpaces(drbreakboard.Right, pill[0], pill[1])
+	return a, b
+}
+
+// copy a playfield space by space so a preset board can be replayed
+func copyPlayField(playfield *drbreakboard.PlayField) *drbreakboard.PlayField {
+	newField := drbreakboard.NewPlayField(playfield.GetWidth(), playfield.GetHeight())
+	for y := 0; y < playfield.GetHeight(); y++ {
+		for x := 0; x < playfield.GetWidth(); x++ {
+			space, _ := playfield.GetSpaceAtCoordinate(y, x)
+			if space.Content != drbreakboard.Empty {
+				newField.ForcePutSingleSpaceIntoBoard(y, x, space)
+			}
+		}
+	}
+	return newField
+}
+
+// shift every row up by one and put row in at the bottom
+// anything in the top row is pushed off the board, the spawn columns are
+// checked for a top out before every rise so only the sides can go
+func insertRowAtBottom(playfield *drbreakboard.PlayField, row []drbreakboard.Space) error {
+	if len(row) != playfield.GetWidth() {
+		return errors.New("row is not the playfield width")
+	}
+
+	for y := 0; y < playfield.GetBottomRowIndex(); y++ {
+		for x := 0; x < playfield.GetWidth(); x++ {
+			space, _ := playfield.GetSpaceAtCoordinate(y+1, x)
+
+			// a half linked up into the row being pushed off loses its partner
+			if y == 0 && space.Content == drbreakboard.Pill && space.Linkage == drbreakboard.Up {
+				space.Linkage = drbreakboard.Unlinked
+			}
+
+			playfield.ForcePutSingleSpaceIntoBoard(y, x, space)
+		}
+	}
+
+	for x, space := range row {
+		playfield.ForcePutSingleSpaceIntoBoard(playfield.GetBottomRowIndex(), x, space)
+	}
+
+	return nil
+}
+
+// make a partly filled row of viruses that won't clear on its own
+// once it's pushed under the current bottom row
+func generateVirusRow(playfield *drbreakboard.PlayField, virusRand *rand.Rand) []drbreakboard.Space {
+	virusTypes := []drbreakboard.SpaceColor{drbreakboard.Yellow, drbreakboard.Red, drbreakboard.Blue}
+	bottomRow := playfield.GetBottomRowIndex()
+
+	row := make([]drbreakboard.Space, playfield.GetWidth())
+	for x := range row {
+		row[x] = drbreakboard.Space{Content: drbreakboard.Empty}
+
+		// leave roughly a third of the row open
+		if virusRand.Intn(3) == 0 {
+			continue
+		}
+
+		// skip colours that would make three in a row with the two spaces above
+		// or the two viruses to the left
+		above, _ := playfield.GetSpaceAtCoordinate(bottomRow, x)
+		aboveTwo, _ := playfield.GetSpaceAtCoordinate(bottomRow-1, x)
+
+		start := virusRand.Intn(len(virusTypes))
+		for i := range virusTypes {
+			color := virusTypes[(start+i)%len(virusTypes)]
+
+			if above.Content != drbreakboard.Empty && aboveTwo.Content != drbreakboard.Empty &&
+				above.Color == color && aboveTwo.Color == color {
+				continue
+			}
+
+			if x >= 2 && row[x-1].Content == drbreakboard.Virus && row[x-2].Content == drbreakboard.Virus &&
+				row[x-1].Color == color && row[x-2].Color == color {
+				continue
+			}
+
+			row[x], _ = drbreakboard.MakeVirus(color)
+			break
+		}
+	}
+
+	return row
+}
+
+func TicksToDuration(ticks int) time.Duration {
+	return time.Duration(ticks) * time.Second / TicksPerSecond
+}
+
 func populateBoardViruses(playfield *drbreakboard.PlayField, level int, seedInt int64) {
 	// 20 is max level
 	if level > 20 {
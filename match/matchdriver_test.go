@@ -0,0 +1,216 @@
+package match
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/drbreakboard"
+)
+
+// the player across the ring gets the drops, or the next live one to their right
+func TestModHalfThenRightDrops(t *testing.T) {
+	tests := []struct {
+		name    string
+		players int
+		dropper int
+		out     []int // players already topped out
+		victims []int // players already dropped on this clear
+		want    int
+	}{
+		{"4 players", 4, 1, nil, nil, 3},
+		{"5 players", 5, 0, nil, nil, 2},
+		{"5 players wrapping", 5, 3, nil, nil, 0},
+		{"6 players", 6, 4, nil, nil, 1},
+		{"7 players", 7, 6, nil, nil, 2},
+		{"8 players", 8, 5, nil, nil, 1},
+		{"skips a topped out player", 8, 0, []int{4}, nil, 5},
+		{"skips earlier victims", 8, 0, nil, []int{4, 5}, 6},
+		{"wraps past the end", 8, 2, []int{6}, []int{7}, 0},
+		{"skips the dropper", 5, 0, []int{2, 3, 4}, nil, 1},
+		{"nobody left", 5, 0, []int{1, 2, 3}, []int{4}, -1},
+	}
+
+	clears := []drbreakboard.SpaceColor{drbreakboard.Yellow, drbreakboard.Red}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := NewDriver()
+			for i := 0; i < tt.players; i++ {
+				md.AddPlayer()
+			}
+			md.StartMatch()
+
+			for _, index := range tt.out {
+				md.playerStates[index].currentAction = FilledBoard
+			}
+			victims := make(map[int]bool)
+			for _, index := range tt.victims {
+				victims[index] = true
+			}
+
+			got, err := md.applyDrops(tt.dropper, clears, ModHalfThenRight, victims)
+			if tt.want < 0 {
+				if err == nil {
+					t.Errorf("dropped on player %d, want no target", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("dropped on player %d, want %d", got, tt.want)
+			}
+			if drops := md.playerStates[got].storedGarbageDrops; len(drops) != 1 || len(drops[0]) != len(clears) {
+				t.Errorf("target has drops %v, want one drop of %d", drops, len(clears))
+			}
+		})
+	}
+}
+
+// a rise pushes the sides of the top row off the board, only the spawn columns top a player out
+func TestRiseTopOutOnlyAtSpawnColumns(t *testing.T) {
+	tests := []struct {
+		name      string
+		x         int
+		toppedOut bool
+	}{
+		{"left edge", 0, false},
+		{"left of spawn", 2, false},
+		{"spawn left", 3, true},
+		{"spawn right", 4, true},
+		{"right edge", BoardWidth - 1, false},
+	}
+
+	virus := drbreakboard.Space{Content: drbreakboard.Virus, Color: drbreakboard.Red}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := NewDriver()
+			md.AddPlayer()
+			md.AddPlayer()
+			md.StartMatch()
+			md.TakeEvents()
+
+			ps := md.playerStates[0]
+			ps.playfield.ForcePutSingleSpaceIntoBoard(0, tt.x, virus)
+			ps.pendingRises = 1
+			ps.currentAction = ReadyForNext
+
+			md.ApplyTick(map[int][]GamepadEvent{})
+
+			toppedOut := false
+			for _, event := range md.TakeEvents() {
+				if event.PlayerIndex == 0 && event.Kind == TopOutMatchEvent {
+					toppedOut = true
+				}
+			}
+			if toppedOut != tt.toppedOut || (ps.currentAction == FilledBoard) != tt.toppedOut {
+				t.Fatalf("topped out %v on action %v, want topped out %v", toppedOut, ps.currentAction, tt.toppedOut)
+			}
+
+			space, _ := ps.playfield.GetSpaceAtCoordinate(0, tt.x)
+			if tt.toppedOut {
+				// the board is left as it was for the top out to show
+				if space != virus {
+					t.Errorf("top row space is %+v, want the virus left where it was", space)
+				}
+				if !md.MatchEnded || md.Winner != 1 {
+					t.Errorf("match ended %v with winner %d, want player 2 to win", md.MatchEnded, md.Winner+1)
+				}
+				return
+			}
+
+			if ps.pendingRises != 0 {
+				t.Errorf("%d rises still pending, want the row risen", ps.pendingRises)
+			}
+			if md.MatchEnded {
+				t.Error("match ended on a rise that only pushed off the side of the top row")
+			}
+		})
+	}
+}
+
+// holding a direction counts as one input however many times it repeats
+func TestCountInputsSkipsRepeats(t *testing.T) {
+	md := NewDriver()
+	md.AddPlayer()
+	md.AddPlayer()
+	md.StartMatch()
+	md.ApplyTick(map[int][]GamepadEvent{})
+
+	ps := md.playerStates[0]
+	if ps.currentAction != PlacingPill {
+		t.Fatalf("player is on action %v, want placing a pill", ps.currentAction)
+	}
+
+	ar := NewAutoRepeater(DefaultAutoRepeat)
+	for tick := 0; tick < 30; tick++ {
+		pressed := []GamepadEvent{LeftPressed}
+		if tick == 0 {
+			pressed = append(pressed, LeftJustPressed)
+		}
+		md.CountInputs(map[int][]GamepadEvent{0: pressed})
+
+		inputs := map[int][]GamepadEvent{0: ar.Apply(pressed)}
+		md.ApplyInputs(inputs)
+		md.ApplyTick(inputs)
+	}
+
+	if ps.stats.pillInputs != 1 {
+		t.Errorf("counted %d inputs, want 1", ps.stats.pillInputs)
+	}
+}
+
+// only a player who topped out has a top out row
+func TestTopOutRowOnlyForToppedOut(t *testing.T) {
+	md := NewDriver()
+	md.AddPlayer()
+	md.AddPlayer()
+	md.StartMatch()
+	md.topOut(0)
+
+	loser, _ := md.GetPlayerStats(0)
+	if loser.TopOutRow == nil {
+		t.Error("topped out player has no top out row")
+	}
+	winner, _ := md.GetPlayerStats(1)
+	if winner.TopOutRow != nil {
+		t.Errorf("winner has top out row %d, want none", *winner.TopOutRow)
+	}
+}
+
+// with the default drop settings, down drops a row as it's pressed and then every 5 ticks held
+func TestSoftDropCadence(t *testing.T) {
+	board, err := ParseBoard(strings.NewReader(strings.Repeat("................\n", BoardHeight-1) + "R*..............\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := NewDriver()
+	md.AddPlayer()
+	md.AddPlayer()
+	if err := md.SetPlayerBoard(0, board); err != nil {
+		t.Fatal(err)
+	}
+	md.StartMatch()
+	md.ApplyTick(map[int][]GamepadEvent{})
+
+	ar := NewAutoRepeater(DefaultAutoRepeat)
+	drops := make([]int, 0)
+	for tick := 0; tick < 16; tick++ {
+		pressed := []GamepadEvent{DownPressed}
+		if tick == 0 {
+			pressed = append(pressed, DownJustPressed)
+		}
+
+		row := md.GetActivePillLocation(0)[0]
+		md.ApplyTick(map[int][]GamepadEvent{0: ar.Apply(pressed)})
+		if md.GetActivePillLocation(0)[0] > row {
+			drops = append(drops, tick)
+		}
+	}
+
+	if want := []int{0, 5, 10, 15}; !reflect.DeepEqual(drops, want) {
+		t.Errorf("dropped on ticks %v, want %v", drops, want)
+	}
+}
@@ -0,0 +1,68 @@
+package match
+
+import "example.com/drbreakboard"
+
+type MatchEventKind int
+
+const (
+	ClearMatchEvent   MatchEventKind = iota // spaces cleared, Spaces hold what was there
+	FallMatchEvent                          // spaces fell a row, Spaces are where they landed
+	GarbageMatchEvent                       // garbage put into the top row
+	TopOutMatchEvent                        // the board filled up
+)
+
+// one space on a board and what's in it
+type boardSpace struct {
+	Y     int
+	X     int
+	Space drbreakboard.Space
+}
+
+// something that happened to a player's board this tick, for animating
+type MatchEvent struct {
+	PlayerIndex int
+	Kind        MatchEventKind
+	Spaces      []boardSpace
+	Ticks       int // ticks until the board moves again
+}
+
+func (md *Driver) emit(event MatchEvent) {
+	md.events = append(md.events, event)
+}
+
+// events since the last call, oldest first
+func (md *Driver) TakeEvents() []MatchEvent {
+	events := md.events
+	md.events = nil
+	return events
+}
+
+// what one board iteration did, before is a copy from before it ran
+func (md *Driver) emitIteration(playerIndex int, before *drbreakboard.PlayField,
+	after *drbreakboard.PlayField, cleared bool) {
+	event := MatchEvent{PlayerIndex: playerIndex, Kind: FallMatchEvent, Ticks: fallTick + 1}
+	if cleared {
+		event.Kind = ClearMatchEvent
+	}
+
+	for y := 0; y < after.GetHeight(); y++ {
+		for x := 0; x < after.GetWidth(); x++ {
+			old, _ := before.GetSpaceAtCoordinate(y, x)
+			now, _ := after.GetSpaceAtCoordinate(y, x)
+			if old == now {
+				continue
+			}
+
+			// clears empty spaces out, falls fill spaces in from the row above
+			if cleared && now.Content == drbreakboard.Empty {
+				event.Spaces = append(event.Spaces, boardSpace{y, x, old})
+			} else if !cleared && now.Content != drbreakboard.Empty {
+				event.Spaces = append(event.Spaces, boardSpace{y, x, now})
+			}
+		}
+	}
+
+	if len(event.Spaces) > 0 {
+		md.emit(event)
+	}
+}
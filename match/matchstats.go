@@ -0,0 +1,124 @@
+package match
+
+import (
+	"time"
+
+	"example.com/drbreakboard"
+)
+
+// PlayerStats is how one player's match went
+// garbage maps are keyed by the opponent's player index
+type PlayerStats struct {
+	PillsPlaced          int           `json:"pillsPlaced"`
+	VirusesCleared       int           `json:"virusesCleared"`
+	LongestChain         int           `json:"longestChain"`
+	GarbageSent          map[int]int   `json:"garbageSent"`
+	GarbageReceived      map[int]int   `json:"garbageReceived"`
+	TimeToFirstClear     time.Duration `json:"timeToFirstClear"`    // zero if nothing was cleared
+	TopOutRow            *int          `json:"topOutRow,omitempty"` // highest occupied row when they topped out, nil if they didn't
+	AveragePlacementTime time.Duration `json:"averagePlacementTime"`
+	InputsPerPill        float64       `json:"inputsPerPill"`
+}
+
+// raw counters kept by the match driver while the match runs
+type statsTracker struct {
+	pillsPlaced     int
+	virusesCleared  int
+	longestChain    int
+	currentChain    int
+	garbageSent     map[int]int
+	garbageReceived map[int]int
+	firstClearTick  int // -1 until the first clear
+	pillSpawnTick   int
+	placementTicks  int // total ticks spent placing pills
+	pillInputs      int // moves and rotations pressed while placing pills, repeats don't count
+	topOutRow       int // -1 unless they topped out
+}
+
+func newStatsTracker() statsTracker {
+	return statsTracker{
+		garbageSent:     make(map[int]int),
+		garbageReceived: make(map[int]int),
+		firstClearTick:  -1,
+		topOutRow:       -1,
+	}
+}
+
+func (st *statsTracker) addClear(virusesCleared int, matchTicks int) {
+	st.virusesCleared += virusesCleared
+
+	st.currentChain++
+	if st.currentChain > st.longestChain {
+		st.longestChain = st.currentChain
+	}
+
+	if st.firstClearTick < 0 {
+		st.firstClearTick = matchTicks
+	}
+}
+
+// board settled, the next clear starts a new chain
+func (st *statsTracker) endChain() {
+	st.currentChain = 0
+}
+
+func (st *statsTracker) toPlayerStats() PlayerStats {
+	stats := PlayerStats{
+		PillsPlaced:     st.pillsPlaced,
+		VirusesCleared:  st.virusesCleared,
+		LongestChain:    st.longestChain,
+		GarbageSent:     make(map[int]int),
+		GarbageReceived: make(map[int]int),
+	}
+
+	if st.topOutRow >= 0 {
+		topOutRow := st.topOutRow
+		stats.TopOutRow = &topOutRow
+	}
+
+	for opponent, count := range st.garbageSent {
+		stats.GarbageSent[opponent] = count
+	}
+
+	for opponent, count := range st.garbageReceived {
+		stats.GarbageReceived[opponent] = count
+	}
+
+	if st.firstClearTick >= 0 {
+		stats.TimeToFirstClear = TicksToDuration(st.firstClearTick)
+	}
+
+	if st.pillsPlaced > 0 {
+		stats.AveragePlacementTime = TicksToDuration(st.placementTicks) / time.Duration(st.pillsPlaced)
+		stats.InputsPerPill = float64(st.pillInputs) / float64(st.pillsPlaced)
+	}
+
+	return stats
+}
+
+// total pieces in a garbage map
+func SumGarbage(garbage map[int]int) int {
+	total := 0
+	for _, count := range garbage {
+		total += count
+	}
+	return total
+}
+
+// top row with anything in it, or the board height if the board is empty
+func highestOccupiedRow(playfield *drbreakboard.PlayField) int {
+	if playfield == nil {
+		return BoardHeight
+	}
+
+	for y := 0; y < playfield.GetHeight(); y++ {
+		for x := 0; x < playfield.GetWidth(); x++ {
+			space, _ := playfield.GetSpaceAtCoordinate(y, x)
+			if space.Content != drbreakboard.Empty {
+				return y
+			}
+		}
+	}
+
+	return playfield.GetHeight()
+}
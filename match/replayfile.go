@@ -0,0 +1,300 @@
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Replay files are json describing a match, its players and the inputs each of them played:
+//
+//	{
+//	  "seed": 42,
+//	  "mode": "versus",
+//	  "players": [
+//	    {
+//	      "board": "level.txt",
+//	      "level": 10,
+//	      "speed": "Med",
+//	      "inputBuffer": false,
+//	      "autoRepeat": {"shiftDelay": 16, "shiftRate": 6, "dropDelay": 5, "dropRate": 5},
+//	      "inputs": [
+//	        {"tick": 30, "action": "left", "state": "pressed"},
+//	        {"tick": 45, "action": "left", "state": "released"}
+//	      ],
+//	      "stats": {"pillsPlaced": 12, "virusesCleared": 4}
+//	    }
+//	  ]
+//	}
+//
+// board is a board file relative to the replay, leave it out for a board made from the seed and level.
+// mode is versus or survival, versus if it's left out. speed defaults to Med and autoRepeat to the
+// default repeat. Actions are the names used for bindings, states are pressed or released and an
+// action stays held in between. held is an action that was already down when it was first
+// recorded, e.g. from before the match started, it stays down without a press. Ticks count from the start of the match at 60 a second.
+// stats is how the player's match went, written with the replay and filled in again when it's played.
+
+type ReplayInput struct {
+	Tick   int    `json:"tick"`
+	Action string `json:"action"`
+	State  string `json:"state"`
+}
+
+type ReplayPlayer struct {
+	Board       string             `json:"board,omitempty"`
+	Level       int                `json:"level"`
+	Speed       string             `json:"speed"`
+	InputBuffer bool               `json:"inputBuffer"`
+	AutoRepeat  AutoRepeatSettings `json:"autoRepeat"`
+	Inputs      []ReplayInput      `json:"inputs"`
+	Stats       *PlayerStats       `json:"stats,omitempty"`
+
+	board  *BoardFile   // loaded from Board, nil for a generated board
+	speed  PlayerSpeed  // parsed from Speed
+	events []InputEvent // parsed from Inputs, in tick order
+}
+
+// a recorded match as it is saved, one player entry per board
+type ReplayFile struct {
+	Seed    int64          `json:"seed"`
+	Mode    string         `json:"mode"`
+	Players []ReplayPlayer `json:"players"`
+
+	mode MatchMode // parsed from Mode
+}
+
+var replayModeNames = [...]string{VersusMode: "versus", SurvivalMode: "survival"}
+var replayStateNames = [...]string{InputPressed: "pressed", InputReleased: "released", InputHeld: "held"}
+
+func LoadReplayFile(filePath string) (*ReplayFile, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &ReplayFile{Mode: replayModeNames[VersusMode]}
+	if err := json.Unmarshal(data, rf); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	modeFound := false
+	for mode, name := range replayModeNames {
+		if name == rf.Mode {
+			rf.mode = MatchMode(mode)
+			modeFound = true
+		}
+	}
+	if !modeFound {
+		return nil, fmt.Errorf("%s: unknown mode %s", filePath, rf.Mode)
+	}
+
+	if len(rf.Players) == 0 || len(rf.Players) > MaxPlayers {
+		return nil, fmt.Errorf("%s: a replay needs 1 to %d players", filePath, MaxPlayers)
+	}
+
+	for i := range rf.Players {
+		if err := rf.Players[i].load(filePath); err != nil {
+			return nil, fmt.Errorf("%s: player %d: %w", filePath, i+1, err)
+		}
+	}
+
+	return rf, nil
+}
+
+// fill in the parsed fields and defaults for one player
+func (rp *ReplayPlayer) load(filePath string) error {
+	var err error
+	if rp.Board != "" {
+		boardPath := rp.Board
+		if !filepath.IsAbs(boardPath) {
+			boardPath = filepath.Join(filepath.Dir(filePath), boardPath)
+		}
+		rp.board, err = LoadBoardFile(boardPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rp.Speed == "" {
+		rp.Speed = SpeedNames[SpeedMed]
+	}
+	speedFound := false
+	for speed, name := range SpeedNames {
+		if name == rp.Speed {
+			rp.speed = PlayerSpeed(speed)
+			speedFound = true
+		}
+	}
+	if !speedFound {
+		return fmt.Errorf("unknown speed %s", rp.Speed)
+	}
+
+	if rp.AutoRepeat == (AutoRepeatSettings{}) {
+		rp.AutoRepeat = DefaultAutoRepeat
+	}
+	rp.AutoRepeat.Clamp()
+
+	lastTick := 0
+	rp.events = make([]InputEvent, 0, len(rp.Inputs))
+	for i, input := range rp.Inputs {
+		action, err := ActionFromName(input.Action)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i+1, err)
+		}
+		state, err := stateFromName(input.State)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i+1, err)
+		}
+		if input.Tick < lastTick {
+			return fmt.Errorf("input %d: ticks go backwards", i+1)
+		}
+		lastTick = input.Tick
+
+		rp.events = append(rp.events, InputEvent{Kind: ReplayDevice, Tick: input.Tick, Action: action, State: state})
+	}
+	return nil
+}
+
+func stateFromName(name string) (InputState, error) {
+	for state, stateName := range replayStateNames {
+		if stateName == name {
+			return InputState(state), nil
+		}
+	}
+	return InputPressed, fmt.Errorf("unknown state %s", name)
+}
+
+func SaveReplayFile(filePath string, rf *ReplayFile) error {
+	data, err := json.MarshalIndent(rf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0o644)
+}
+
+// tick of the last input of any player, 0 with no inputs
+func (rf *ReplayFile) LastInputTick() int {
+	lastTick := 0
+	for _, player := range rf.Players {
+		if len(player.events) > 0 && player.events[len(player.events)-1].Tick > lastTick {
+			lastTick = player.events[len(player.events)-1].Tick
+		}
+	}
+	return lastTick
+}
+
+func (rf *ReplayFile) PlayerCount() int {
+	return len(rf.Players)
+}
+
+// take each player's stats from how the match went in md
+func (rf *ReplayFile) SetStats(md *Driver) {
+	for i := range rf.Players {
+		stats, err := md.GetPlayerStats(i)
+		if err != nil {
+			continue
+		}
+		rf.Players[i].Stats = &stats
+	}
+}
+
+// plays a replay through a match driver without drawing anything
+// the match sees the inputs as it would in a game, auto repeat included
+type ReplayMatch struct {
+	replay      *ReplayFile
+	matchDriver *Driver
+	sources     []*ReplaySource
+	repeaters   []*AutoRepeater
+	held        [][InputActionCount]bool
+	tick        int
+}
+
+func NewReplayMatch(rf *ReplayFile) (*ReplayMatch, error) {
+	md := NewDriver()
+	md.SetSeed(rf.Seed)
+	md.SetMode(rf.mode)
+
+	rm := &ReplayMatch{replay: rf, matchDriver: md, held: make([][InputActionCount]bool, len(rf.Players))}
+	for i, player := range rf.Players {
+		md.AddPlayer()
+		if err := md.SetPlayerBoard(i, player.board); err != nil {
+			return nil, fmt.Errorf("player %d: %w", i+1, err)
+		}
+		_ = md.SetLevel(i, player.Level)
+		_ = md.SetSpeed(i, player.speed)
+		_ = md.SetInputBuffer(i, player.InputBuffer)
+
+		rm.sources = append(rm.sources, NewReplaySource(i, player.events))
+		rm.repeaters = append(rm.repeaters, NewAutoRepeater(player.AutoRepeat))
+	}
+	md.StartMatch()
+
+	return rm, nil
+}
+
+// play one tick, does nothing once the match is over
+// the replay's stats are filled in from the match when it ends
+func (rm *ReplayMatch) Step() {
+	if rm.matchDriver.MatchEnded {
+		return
+	}
+
+	pressed := map[int][]GamepadEvent{}
+	for playerIndex, source := range rm.sources {
+		held := &rm.held[playerIndex]
+		changed := map[InputAction]InputEvent{}
+		for _, event := range source.Poll(rm.tick) {
+			held[event.Action] = event.State != InputReleased
+			changed[event.Action] = event
+		}
+
+		// in action order, the way devices send them
+		events := make([]InputEvent, 0)
+		for action := InputAction(0); action < InputActionCount; action++ {
+			if event, hasChange := changed[action]; hasChange {
+				events = append(events, event)
+			} else if held[action] {
+				events = append(events, InputEvent{Kind: ReplayDevice, Tick: rm.tick, Action: action, State: InputHeld})
+			}
+		}
+		pressed[playerIndex] = GamepadEventsFromInput(events)
+	}
+	rm.matchDriver.CountInputs(pressed)
+
+	inputs := map[int][]GamepadEvent{}
+	for playerIndex, events := range pressed {
+		inputs[playerIndex] = rm.repeaters[playerIndex].Apply(events)
+	}
+
+	rm.matchDriver.ApplyInputs(inputs)
+	rm.matchDriver.ApplyTick(inputs)
+
+	// nothing animates here, so don't let events pile up
+	rm.matchDriver.TakeEvents()
+	rm.tick++
+
+	if rm.matchDriver.MatchEnded {
+		rm.replay.SetStats(rm.matchDriver)
+	}
+}
+
+// play until tick or until the match is over
+func (rm *ReplayMatch) StepTo(tick int) {
+	for rm.tick < tick && !rm.matchDriver.MatchEnded {
+		rm.Step()
+	}
+}
+
+func (rm *ReplayMatch) Tick() int {
+	return rm.tick
+}
+
+func (rm *ReplayMatch) Ended() bool {
+	return rm.matchDriver.MatchEnded
+}
+
+// the driver being played, for reading the boards
+func (rm *ReplayMatch) Driver() *Driver {
+	return rm.matchDriver
+}
@@ -0,0 +1,80 @@
+package match
+
+// builds a replay while a match is played, from the events each player's device sent
+// only what changes is kept, the replay holds an action down between its press and release
+type ReplayRecorder struct {
+	replay *ReplayFile
+	held   [][InputActionCount]bool
+}
+
+// start recording the match md has just started, autoRepeat is each player's repeat settings
+// the match's boards have to be generated ones, a replay can't point at a preset board
+func NewReplayRecorder(md *Driver, autoRepeat []AutoRepeatSettings) *ReplayRecorder {
+	rf := &ReplayFile{Seed: md.Seed, Mode: replayModeNames[md.Mode], mode: md.Mode}
+	for i := range md.playerStates {
+		level, _ := md.GetLevel(i)
+		speed, _ := md.GetSpeed(i)
+		inputBuffer, _ := md.GetInputBuffer(i)
+		player := ReplayPlayer{
+			Level:       level,
+			Speed:       SpeedNames[speed],
+			InputBuffer: inputBuffer,
+			AutoRepeat:  DefaultAutoRepeat,
+			Inputs:      make([]ReplayInput, 0),
+			speed:       speed,
+		}
+		if i < len(autoRepeat) {
+			player.AutoRepeat = autoRepeat[i]
+		}
+		rf.Players = append(rf.Players, player)
+	}
+
+	rr := &ReplayRecorder{replay: rf, held: make([][InputActionCount]bool, len(rf.Players))}
+	return rr
+}
+
+// note the events a player's device sent on the match tick they're applied on
+// an action that's already down when it's first seen is recorded as held, so it doesn't replay as a press
+func (rr *ReplayRecorder) Record(playerIndex int, tick int, events []InputEvent) {
+	if playerIndex < 0 || playerIndex >= len(rr.held) {
+		return
+	}
+	held := &rr.held[playerIndex]
+	player := &rr.replay.Players[playerIndex]
+
+	seen := [InputActionCount]bool{}
+	for _, event := range events {
+		seen[event.Action] = true
+		switch {
+		case event.State == InputPressed:
+			player.add(tick, event.Action, InputPressed)
+			held[event.Action] = true
+		case event.State == InputHeld && !held[event.Action]:
+			player.add(tick, event.Action, InputHeld)
+			held[event.Action] = true
+		case event.State == InputReleased && held[event.Action]:
+			player.add(tick, event.Action, InputReleased)
+			held[event.Action] = false
+		}
+	}
+
+	// a device that stopped sending, e.g. one that was unplugged, let go of everything
+	for action := InputAction(0); action < InputActionCount; action++ {
+		if held[action] && !seen[action] {
+			player.add(tick, action, InputReleased)
+			held[action] = false
+		}
+	}
+}
+
+func (rp *ReplayPlayer) add(tick int, action InputAction, state InputState) {
+	event := InputEvent{Kind: ReplayDevice, Tick: tick, Action: action, State: state}
+	rp.events = append(rp.events, event)
+	rp.Inputs = append(rp.Inputs, ReplayInput{Tick: tick, Action: InputActionNames[action], State: replayStateNames[state]})
+}
+
+// the replay recorded so far, with each player's stats from md
+func (rr *ReplayRecorder) Replay(md *Driver) *ReplayFile {
+	rr.replay.SetStats(md)
+	return rr.replay
+}
@@ -0,0 +1,143 @@
+package match
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// a device that presses random actions, standing in for a player's controller
+type testSource struct {
+	deviceId int
+	rand     *rand.Rand
+	pressed  [InputActionCount]bool
+	held     [InputActionCount]bool
+}
+
+func (ts *testSource) DeviceId() int {
+	return ts.deviceId
+}
+
+func (ts *testSource) Kind() DeviceKind {
+	return CPUDevice
+}
+
+// flip an action now and then so presses, holds and releases all happen
+func (ts *testSource) Poll(tick int) []InputEvent {
+	if ts.rand.Intn(3) == 0 {
+		action := InputAction(ts.rand.Intn(int(InputActionCount)))
+		if action != ActionStart && action != ActionSelect {
+			ts.pressed[action] = !ts.pressed[action]
+		}
+	}
+	return ActionsToInputEvents(ts, tick, ts.pressed, &ts.held)
+}
+
+// a match recorded the way the game records one plays back the same from the saved file
+func TestRecordedReplayPlaysBackTheSame(t *testing.T) {
+	const players = 2
+	const ticks = 900
+
+	md := NewDriver()
+	md.SetSeed(7)
+	autoRepeat := []AutoRepeatSettings{DefaultAutoRepeat, {ShiftDelay: 8, ShiftRate: 2, DropDelay: 3, DropRate: 1}}
+	sources := make([]*testSource, players)
+	repeaters := make([]*AutoRepeater, players)
+	for i := 0; i < players; i++ {
+		md.AddPlayer()
+		_ = md.SetLevel(i, 3+i)
+		_ = md.SetSpeed(i, PlayerSpeed(i))
+		_ = md.SetInputBuffer(i, i == 1)
+		sources[i] = &testSource{deviceId: 100 + i, rand: rand.New(rand.NewSource(int64(i)))}
+		repeaters[i] = NewAutoRepeater(autoRepeat[i])
+	}
+	md.StartMatch()
+
+	recorder := NewReplayRecorder(md, autoRepeat)
+	for tick := 0; tick < ticks && !md.MatchEnded; tick++ {
+		pressed := map[int][]GamepadEvent{}
+		for i, source := range sources {
+			events := source.Poll(tick)
+			recorder.Record(i, md.MatchTicks, events)
+			pressed[i] = GamepadEventsFromInput(events)
+		}
+		md.CountInputs(pressed)
+
+		inputs := map[int][]GamepadEvent{}
+		for i, events := range pressed {
+			inputs[i] = repeaters[i].Apply(events)
+		}
+		md.ApplyInputs(inputs)
+		md.ApplyTick(inputs)
+		md.TakeEvents()
+	}
+
+	replayPath := filepath.Join(t.TempDir(), "match.json")
+	if err := SaveReplayFile(replayPath, recorder.Replay(md)); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := LoadReplayFile(replayPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rf.Seed != md.Seed || rf.PlayerCount() != players {
+		t.Fatalf("replay has seed %d and %d players, want %d and %d", rf.Seed, rf.PlayerCount(), md.Seed, players)
+	}
+
+	rm, err := NewReplayMatch(rf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm.StepTo(md.MatchTicks)
+
+	if rm.Tick() != md.MatchTicks || rm.Ended() != md.MatchEnded {
+		t.Fatalf("replay stopped at tick %d ended %v, match was at %d ended %v",
+			rm.Tick(), rm.Ended(), md.MatchTicks, md.MatchEnded)
+	}
+	for i := 0; i < players; i++ {
+		want, got := md.GetPlayfield(i), rm.Driver().GetPlayfield(i)
+		for y := 0; y < BoardHeight; y++ {
+			for x := 0; x < BoardWidth; x++ {
+				wantSpace, _ := want.GetSpaceAtCoordinate(y, x)
+				gotSpace, _ := got.GetSpaceAtCoordinate(y, x)
+				if wantSpace != gotSpace {
+					t.Fatalf("player %d space %d,%d is %v, want %v", i+1, y, x, gotSpace, wantSpace)
+				}
+			}
+		}
+
+		wantStats, _ := md.GetPlayerStats(i)
+		gotStats, _ := rm.Driver().GetPlayerStats(i)
+		if !reflect.DeepEqual(wantStats, gotStats) {
+			t.Errorf("player %d stats are %+v, want %+v", i+1, gotStats, wantStats)
+		}
+		if rf.Players[i].Stats == nil || !reflect.DeepEqual(*rf.Players[i].Stats, wantStats) {
+			t.Errorf("player %d saved stats are %+v, want %+v", i+1, rf.Players[i].Stats, wantStats)
+		}
+	}
+}
+
+func TestRecorderKeepsOnlyChanges(t *testing.T) {
+	md := NewDriver()
+	md.AddPlayer()
+	md.StartMatch()
+	recorder := NewReplayRecorder(md, nil)
+
+	held := InputEvent{Action: ActionLeft, State: InputHeld}
+	recorder.Record(0, 0, []InputEvent{held})
+	recorder.Record(0, 1, []InputEvent{held})
+	recorder.Record(0, 2, []InputEvent{{Action: ActionDown, State: InputPressed}, held})
+	recorder.Record(0, 3, nil)
+
+	want := []ReplayInput{
+		{Tick: 0, Action: "left", State: "held"},
+		{Tick: 2, Action: "down", State: "pressed"},
+		{Tick: 3, Action: "left", State: "released"},
+		{Tick: 3, Action: "down", State: "released"},
+	}
+	got := recorder.Replay(md).Players[0].Inputs
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded %+v, want %+v", got, want)
+	}
+}
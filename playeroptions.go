@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/drbreaktime/match"
+)
+
+// per player settings, saved with the profile
+type playerOptions struct {
+	AutoRepeat   match.AutoRepeatSettings `json:"autoRepeat"`
+	InputBuffer  bool                     `json:"inputBuffer"`  // keep moves pressed between pills for the next one
+	GhostPill    bool                     `json:"ghostPill"`    // show where the pill will land
+	Patterns     bool                     `json:"patterns"`     // shapes on viruses and pills so colors aren't the only difference
+	HighContrast bool                     `json:"highContrast"` // colour-blind friendly palette
+	ColorPreview colorSimulation          `json:"-"`            // only for trying the look out on the options screen
+}
+
+func (options playerOptions) spriteStyle() spriteStyle {
+	return spriteStyle{patterns: options.Patterns, highContrast: options.HighContrast}
+}
+
+func defaultPlayerOptions() playerOptions {
+	return playerOptions{AutoRepeat: match.DefaultAutoRepeat}
+}
+
+// one line on the options screen
+type optionRow struct {
+	name   string
+	value  func(options *playerOptions) string
+	change func(options *playerOptions, delta int)
+}
+
+func ticksOption(name string, ticks func(options *playerOptions) *int) optionRow {
+	return optionRow{
+		name: name,
+		value: func(options *playerOptions) string {
+			return fmt.Sprintf("%d", *ticks(options))
+		},
+		change: func(options *playerOptions, delta int) {
+			*ticks(options) += delta
+			options.AutoRepeat.Clamp()
+		},
+	}
+}
+
+func toggleOption(name string, setting func(options *playerOptions) *bool) optionRow {
+	return optionRow{
+		name: name,
+		value: func(options *playerOptions) string {
+			if *setting(options) {
+				return "On"
+			}
+			return "Off"
+		},
+		change: func(options *playerOptions, delta int) {
+			*setting(options) = !*setting(options)
+		},
+	}
+}
+
+var playerOptionRows = []optionRow{
+	ticksOption("Shift delay", func(options *playerOptions) *int { return &options.AutoRepeat.ShiftDelay }),
+	ticksOption("Shift rate", func(options *playerOptions) *int { return &options.AutoRepeat.ShiftRate }),
+	ticksOption("Drop delay", func(options *playerOptions) *int { return &options.AutoRepeat.DropDelay }),
+	ticksOption("Drop rate", func(options *playerOptions) *int { return &options.AutoRepeat.DropRate }),
+	toggleOption("Buffer", func(options *playerOptions) *bool { return &options.InputBuffer }),
+	toggleOption("Ghost", func(options *playerOptions) *bool { return &options.GhostPill }),
+	toggleOption("Patterns", func(options *playerOptions) *bool { return &options.Patterns }),
+	toggleOption("Contrast", func(options *playerOptions) *bool { return &options.HighContrast }),
+	{
+		name: "Preview",
+		value: func(options *playerOptions) string {
+			return colorSimulationNames[options.ColorPreview]
+		},
+		change: func(options *playerOptions, delta int) {
+			preview := (int(options.ColorPreview) + delta + int(colorSimulationCount)) % int(colorSimulationCount)
+			options.ColorPreview = colorSimulation(preview)
+		},
+	},
+}
+
+func (g *Game) updateOptionChoice(playerIndex int, events []match.GamepadEvent) {
+	setup := g.playerSetups[playerIndex]
+
+	for _, event := range events {
+		switch event {
+		case match.UpJustPressed:
+			setup.optionCursor = (setup.optionCursor + len(playerOptionRows) - 1) % len(playerOptionRows)
+		case match.DownJustPressed:
+			setup.optionCursor = (setup.optionCursor + 1) % len(playerOptionRows)
+		case match.LeftJustPressed:
+			playerOptionRows[setup.optionCursor].change(&setup.options, -1)
+		case match.RightJustPressed:
+			playerOptionRows[setup.optionCursor].change(&setup.options, 1)
+		case match.StartJustPressed, match.SecondaryJustPressed:
+			// back to the level screen, keeping the changes
+			_ = g.matchDriver.SetInputBuffer(playerIndex, setup.options.InputBuffer)
+			g.savePlayerOptions(playerIndex)
+			setup.options.ColorPreview = NoSimulation
+			setup.phase = ChoosingLevel
+			return
+		}
+	}
+
+	// the preview on the options screen follows the changes as they're made
+	g.playfieldViz[playerIndex].SetSpriteStyle(setup.options.spriteStyle())
+}
+
+// option names and values for drawing
+func (setup *playerSetup) optionLines() ([]string, []string) {
+	names := make([]string, len(playerOptionRows))
+	values := make([]string, len(playerOptionRows))
+	for i, row := range playerOptionRows {
+		names[i] = row.name
+		values[i] = row.value(&setup.options)
+	}
+	return names, values
+}
+
+func (g *Game) savePlayerOptions(playerIndex int) {
+	profile := g.getPlayerProfile(playerIndex)
+	if profile == nil {
+		return
+	}
+
+	profile.Options = g.playerSetups[playerIndex].options
+	g.saveProfiles()
+}
+
+// the player's options, the defaults for anyone who hasn't set up, like editor test play
+func (g *Game) getPlayerOptions(playerIndex int) playerOptions {
+	if setup, hasSetup := g.playerSetups[playerIndex]; hasSetup {
+		return setup.options
+	}
+	return defaultPlayerOptions()
+}
+
+// auto repeat for the player, made on first use from their options
+func (g *Game) getAutoRepeater(playerIndex int) *match.AutoRepeater {
+	ar, exists := g.autoRepeaters[playerIndex]
+	if exists {
+		return ar
+	}
+
+	ar = match.NewAutoRepeater(g.getPlayerOptions(playerIndex).AutoRepeat)
+	g.autoRepeaters[playerIndex] = ar
+	return ar
+}
+
+// fresh repeaters from each player's settings for a match that's starting,
+// so nothing held or changed before it carries into the match
+func (g *Game) resetAutoRepeaters() {
+	g.autoRepeaters = map[int]*match.AutoRepeater{}
+	for playerIndex := 0; playerIndex < g.playerCount; playerIndex++ {
+		g.autoRepeaters[playerIndex] = match.NewAutoRepeater(g.getPlayerOptions(playerIndex).AutoRepeat)
+	}
+}
+
+// player inputs with held directions repeating
+func (g *Game) applyAutoRepeat(playerIndexInputs map[int][]match.GamepadEvent) map[int][]match.GamepadEvent {
+	for playerIndex := 0; playerIndex < g.playerCount; playerIndex++ {
+		playerIndexInputs[playerIndex] = g.getAutoRepeater(playerIndex).Apply(playerIndexInputs[playerIndex])
+	}
+	return playerIndexInputs
+}
+
+// show the landing spot for players who want it
+func (g *Game) updateGhost(playerIndex int) {
+	location, inPlay := g.matchDriver.GetGhostPillLocation(playerIndex)
+	visible := inPlay && g.getPlayerOptions(playerIndex).GhostPill
+	g.playfieldViz[playerIndex].UpdateGhost(g.matchDriver.GetActivePill(playerIndex), location, visible)
+}
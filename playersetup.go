@@ -0,0 +1,303 @@
+package main
+
+import (
+	"time"
+
+	"example.com/drbreaktime/match"
+	"github.com/rs/zerolog/log"
+)
+
+type SetupPhase int
+
+// steps a joined player goes through on the player assignment screen
+const (
+	ChoosingProfile SetupPhase = iota
+	EnteringName
+	ChoosingLevel
+	ChoosingOptions
+)
+
+const guestProfileLabel = "Guest"
+const newProfileLabel = "New..."
+const nameChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+type playerSetup struct {
+	phase         SetupPhase
+	profileCursor int    // index into the profile options
+	name          string // new profile name entered so far
+	nameChar      int    // index into nameChars of the letter being picked
+	profileName   string // picked profile, empty for a guest
+	message       string // last problem to show the player
+	options       playerOptions
+	optionCursor  int
+}
+
+func NewPlayerSetup() *playerSetup {
+	setup := &playerSetup{phase: ChoosingProfile, options: defaultPlayerOptions()}
+	return setup
+}
+
+// name shown over the player's column
+func (setup *playerSetup) DisplayName() string {
+	if setup.profileName == "" {
+		return guestProfileLabel
+	}
+	return setup.profileName
+}
+
+// guest first, then every saved profile, then the new profile option
+// tournament matches only offer the two players called up
+func (g *Game) profileOptions() []string {
+	if g.tournamentMatch != nil {
+		return g.tournamentMatch.Players[:]
+	}
+
+	options := []string{guestProfileLabel}
+	options = append(options, g.profileStore.Names()...)
+	options = append(options, newProfileLabel)
+	return options
+}
+
+// check whether another player already picked the profile, they keep it until they back out to the profile list
+func (g *Game) isProfileTaken(playerIndex int, name string) bool {
+	for otherIndex, setup := range g.playerSetups {
+		if otherIndex != playerIndex && name != "" && setup.profileName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// profile the player picked, nil for guests
+func (g *Game) getPlayerProfile(playerIndex int) *playerProfile {
+	setup, exists := g.playerSetups[playerIndex]
+	if !exists || setup.profileName == "" {
+		return nil
+	}
+	return g.profileStore.Get(setup.profileName)
+}
+
+func (g *Game) updateProfileChoice(playerIndex int, events []match.GamepadEvent) {
+	setup := g.playerSetups[playerIndex]
+	options := g.profileOptions()
+
+	for _, event := range events {
+		switch event {
+		case match.UpJustPressed:
+			setup.profileCursor = (setup.profileCursor + len(options) - 1) % len(options)
+		case match.DownJustPressed:
+			setup.profileCursor = (setup.profileCursor + 1) % len(options)
+		case match.SecondaryJustPressed:
+			// leave, freeing the slot
+			g.removePlayer(playerIndex)
+			return
+		case match.PrimaryJustPressed:
+			option := options[setup.profileCursor]
+			switch {
+			case option == guestProfileLabel:
+				g.choosePlayerProfile(playerIndex, nil)
+			case option == newProfileLabel:
+				setup.phase = EnteringName
+				setup.name = ""
+				setup.nameChar = 0
+				setup.message = ""
+			case g.isProfileTaken(playerIndex, option):
+				setup.message = "Taken"
+				continue
+			default:
+				g.choosePlayerProfile(playerIndex, g.profileStore.Get(option))
+			}
+			return
+		}
+	}
+}
+
+func (g *Game) updateNameEntry(playerIndex int, events []match.GamepadEvent) {
+	setup := g.playerSetups[playerIndex]
+
+	for _, event := range events {
+		switch event {
+		case match.UpJustPressed:
+			setup.nameChar = (setup.nameChar + 1) % len(nameChars)
+		case match.DownJustPressed:
+			setup.nameChar = (setup.nameChar + len(nameChars) - 1) % len(nameChars)
+		case match.PrimaryJustPressed, match.RightJustPressed:
+			if len(setup.name) < maxProfileNameLength {
+				setup.name += string(nameChars[setup.nameChar])
+			}
+		case match.SecondaryJustPressed, match.LeftJustPressed:
+			// backing out of an empty name goes back to the profile list
+			if len(setup.name) == 0 {
+				setup.phase = ChoosingProfile
+				setup.message = ""
+				return
+			}
+			setup.name = setup.name[:len(setup.name)-1]
+		case match.StartJustPressed:
+			profile, err := g.profileStore.Create(setup.name)
+			if err != nil {
+				setup.message = err.Error()
+				continue
+			}
+			g.saveProfiles()
+			g.choosePlayerProfile(playerIndex, profile)
+			return
+		}
+	}
+}
+
+// move the player on to level select, loading the profile's preferences
+func (g *Game) choosePlayerProfile(playerIndex int, profile *playerProfile) {
+	setup := g.playerSetups[playerIndex]
+
+	setup.profileName = ""
+	setup.options = defaultPlayerOptions()
+	if profile != nil {
+		setup.profileName = profile.Name
+		setup.options = profile.Options
+		_ = g.matchDriver.SetLevel(playerIndex, profile.PreferredLevel)
+		_ = g.matchDriver.SetSpeed(playerIndex, profile.PreferredSpeed)
+	}
+	_ = g.matchDriver.SetInputBuffer(playerIndex, setup.options.InputBuffer)
+	g.playfieldViz[playerIndex].SetSpriteStyle(setup.options.spriteStyle())
+	g.applyProfileBindings()
+
+	setup.message = ""
+	setup.phase = ChoosingLevel
+}
+
+// give each player's controller their profile's bindings, every other controller uses its own
+func (g *Game) applyProfileBindings() {
+	overrides := map[int]deviceBindings{}
+	for playerIndex, controllerId := range g.controllerAssignments {
+		profile := g.getPlayerProfile(playerIndex)
+		if profile == nil {
+			continue
+		}
+
+		actions, exists := profile.Bindings[g.inputDriver.DeviceName(controllerId)]
+		if !exists {
+			continue
+		}
+		bindings, err := parseDeviceBindings(actions)
+		if err != nil {
+			log.Printf("profile %s bindings: %v", profile.Name, err)
+			continue
+		}
+		overrides[controllerId] = bindings
+	}
+	g.inputDriver.SetOverrides(overrides)
+}
+
+func (g *Game) updateLevelChoice(playerIndex int, events []match.GamepadEvent) {
+	setup := g.playerSetups[playerIndex]
+	ready, _ := g.matchDriver.GetPlayerReady(playerIndex)
+
+	// handle level setting and ready buttons
+	for _, event := range events {
+		if event == match.UpJustPressed && !ready {
+			_ = g.matchDriver.ChangeLevel(playerIndex, 1)
+		} else if event == match.DownJustPressed && !ready {
+			_ = g.matchDriver.ChangeLevel(playerIndex, -1)
+		} else if event == match.RightJustPressed && !ready {
+			_ = g.matchDriver.ChangeSpeed(playerIndex, 1)
+		} else if event == match.LeftJustPressed && !ready {
+			_ = g.matchDriver.ChangeSpeed(playerIndex, -1)
+		} else if event == match.PrimaryJustPressed {
+			_ = g.matchDriver.SetPlayerReady(playerIndex, true)
+			g.savePlayerPreferences(playerIndex)
+		} else if event == match.StartJustPressed && !ready {
+			setup.phase = ChoosingOptions
+			setup.optionCursor = 0
+			return
+		} else if event == match.SecondaryJustPressed && ready {
+			_ = g.matchDriver.SetPlayerReady(playerIndex, false)
+		} else if event == match.SecondaryJustPressed {
+			// not ready, back out to pick a different profile
+			setup.phase = ChoosingProfile
+			setup.profileName = ""
+			g.applyProfileBindings()
+			return
+		}
+		ready, _ = g.matchDriver.GetPlayerReady(playerIndex)
+	}
+}
+
+// remember the level and speed the player readied with
+func (g *Game) savePlayerPreferences(playerIndex int) {
+	profile := g.getPlayerProfile(playerIndex)
+	if profile == nil {
+		return
+	}
+
+	profile.PreferredLevel, _ = g.matchDriver.GetLevel(playerIndex)
+	profile.PreferredSpeed, _ = g.matchDriver.GetSpeed(playerIndex)
+	g.saveProfiles()
+}
+
+// add the finished match to each profiled player's lifetime stats
+func (g *Game) recordProfileResults() {
+	md := g.matchDriver
+	recorded := false
+
+	for playerIndex := 0; playerIndex < g.playerCount; playerIndex++ {
+		profile := g.getPlayerProfile(playerIndex)
+		if profile == nil {
+			continue
+		}
+
+		// last player standing wins unless they finished by topping out
+		won := md.Winner == playerIndex
+		var clearTime time.Duration
+		for _, finish := range md.PlayerFinishes {
+			if finish.PlayerIndex != playerIndex {
+				continue
+			}
+			won = finish.Result == match.Cleared
+			if won {
+				clearTime = match.TicksToDuration(md.MatchTicks)
+			}
+		}
+
+		level, _ := md.GetLevel(playerIndex)
+		score, _ := md.GetScore(playerIndex)
+		survived, _, _ := md.GetSurvivalTimers(playerIndex)
+		stats, _ := md.GetPlayerStats(playerIndex)
+
+		profile.RecordMatch(md.Mode, won, clearTime, level, score, survived, stats)
+		recorded = true
+	}
+
+	if recorded && md.Mode == match.VersusMode && g.profileStore.ratingsEnabled {
+		g.recordRatings()
+	}
+
+	if recorded {
+		g.saveProfiles()
+	}
+}
+
+// rate the profiled players in the match against each other
+func (g *Game) recordRatings() {
+	placements := g.matchDriver.GetPlacements()
+
+	profilePlacements := make(map[string]int)
+	for playerIndex := 0; playerIndex < g.playerCount; playerIndex++ {
+		if profile := g.getPlayerProfile(playerIndex); profile != nil {
+			profilePlacements[profile.Name] = placements[playerIndex]
+		}
+	}
+
+	// guests aren't rated, so it takes two profiles to rate a match
+	if len(profilePlacements) < 2 {
+		return
+	}
+
+	g.profileStore.ApplyRatings(profilePlacements, time.Now())
+}
+
+func (g *Game) saveProfiles() {
+	if err := g.profileStore.Save(); err != nil {
+		log.Printf("could not save profiles: %v", err)
+	}
+}
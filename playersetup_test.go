@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// a profile stays taken through every phase after it's picked, until its player backs out to the list
+func TestIsProfileTaken(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase SetupPhase
+		taken bool
+	}{
+		{"choosing level", ChoosingLevel, true},
+		{"choosing options", ChoosingOptions, true},
+		{"back on the profile list", ChoosingProfile, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup := &playerSetup{phase: tt.phase, profileName: "amy"}
+			if tt.phase == ChoosingProfile {
+				setup.profileName = ""
+			}
+			g := &Game{playerSetups: map[int]*playerSetup{0: setup, 1: {phase: ChoosingProfile}}}
+
+			if taken := g.isProfileTaken(1, "amy"); taken != tt.taken {
+				t.Errorf("taken is %v, want %v", taken, tt.taken)
+			}
+			if g.isProfileTaken(0, "amy") {
+				t.Error("a player's own profile counts as taken")
+			}
+		})
+	}
+}
@@ -3,9 +3,10 @@ package main
 import (
 	"fmt"
 	"image/color"
-	"math"
+	"time"
 
 	"example.com/drbreakboard"
+	"example.com/drbreaktime/match"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
 )
@@ -19,8 +20,9 @@ type playfieldState struct {
 }
 
 type playfieldViz struct {
-	imageMap      imageMap
+	atlas         *spriteAtlas
 	fontMap       fontMap
+	scale         float64 // size against a 160 pixel wide column, text and spacing follow it
 	xBuffer       int
 	yBuffer       int
 	xPixelSize    int
@@ -30,14 +32,21 @@ type playfieldViz struct {
 	yOffset       int
 	statusY       int
 	fieldState    [][]playfieldState
+	animations    boardAnimations
+	ghostState    [2]playfieldState // the active pill where it would land
+	ghostPosition [2]int
+	showGhost     bool
 	nextPillState [2]playfieldState
+	spriteStyle   spriteStyle // patterns or high contrast colors for the player
 }
 
-func NewPlayfieldViz(gameImageMap imageMap, gameFontMap fontMap) *playfieldViz {
+func NewPlayfieldViz(atlas *spriteAtlas, gameFontMap fontMap) *playfieldViz {
 	viz := &playfieldViz{}
 
-	viz.imageMap = gameImageMap
+	viz.atlas = atlas
 	viz.fontMap = gameFontMap
+	viz.scale = 1
+	viz.animations = newBoardAnimations()
 
 	return viz
 }
@@ -53,52 +62,133 @@ func (viz *playfieldViz) SetPixelSizeAndOffset(x int, y int, xOffset int, yOffse
 	viz.statusY = viz.yPixelSize/3 - viz.yBuffer // sub out second yBuffer for board bottom border
 	viz.xOffset = xOffset
 	viz.yOffset = yOffset
+
+	viz.scale = float64(x) / basePlayfieldWidth
+	viz.fontMap = scaledFontMap(viz.scale)
+
+	// trim the board so spaces are square, keeping it centred where it was
+	cell := viz.xPixelSize / match.BoardWidth
+	if viz.playfieldY/match.BoardHeight < cell {
+		cell = viz.playfieldY / match.BoardHeight
+	}
+	if cell < 1 {
+		cell = 1
+	}
+
+	xTrim := viz.xPixelSize - cell*match.BoardWidth
+	viz.xPixelSize -= xTrim
+	viz.xOffset += xTrim / 2
+
+	yTrim := viz.playfieldY - cell*match.BoardHeight
+	viz.playfieldY -= yTrim
+	viz.yPixelSize -= yTrim
+	viz.yOffset += yTrim / 2
 }
 
-func (viz *playfieldViz) DrawWaitingPlayerToImage(image *ebiten.Image, playerLevel int, ready bool) {
-	// draw left border
-	geom := ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset), float64(viz.yOffset))
-	pxImage := viz.imageMap["greenPixel"]
-	newImg := ebiten.NewImageFromImage(pxImage)
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+func (viz *playfieldViz) DrawProfileChoiceToImage(image *ebiten.Image, options []string, cursor int, message string) {
+	viz.drawSideBorders(image)
 
-	// draw right border
-	geom = ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset+viz.xBuffer+viz.xPixelSize), float64(viz.yOffset))
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+	textX := viz.xOffset + viz.xBuffer
+	textY := viz.yOffset + viz.yPixelSize/3
 
-	text.Draw(image, "Joined!", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3,
+	text.Draw(image, "Who are\nyou?", viz.fontMap["base"], textX, textY-viz.px(60),
 		color.RGBA{128, 128, 128, 255})
 
-	text.Draw(image, fmt.Sprintf("Level: %d", playerLevel), viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+30,
+	// show a window of options around the cursor
+	const shown = 6
+	first := cursor - shown/2
+	if first > len(options)-shown {
+		first = len(options) - shown
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	for i := first; i < len(options) && i < first+shown; i++ {
+		marker := "  "
+		if i == cursor {
+			marker = "> "
+		}
+		text.Draw(image, marker+options[i], viz.fontMap["base"], textX, textY+(i-first)*viz.px(30),
+			color.RGBA{128, 128, 128, 255})
+	}
+
+	text.Draw(image, message, viz.fontMap["small"], textX, textY+shown*viz.px(30)+viz.px(10),
+		color.RGBA{255, 128, 128, 255})
+}
+
+func (viz *playfieldViz) DrawNameEntryToImage(image *ebiten.Image, name string, currentChar byte, message string) {
+	viz.drawSideBorders(image)
+
+	textX := viz.xOffset + viz.xBuffer
+	textY := viz.yOffset + viz.yPixelSize/3
+
+	text.Draw(image, "Name:", viz.fontMap["base"], textX, textY,
+		color.RGBA{128, 128, 128, 255})
+	text.Draw(image, name+"["+string(currentChar)+"]", viz.fontMap["base"], textX, textY+viz.px(30),
+		color.RGBA{128, 128, 128, 255})
+
+	text.Draw(image, "Up/Down: letter\nA: add letter\nB: delete\nStart: done", viz.fontMap["small"],
+		textX, textY+viz.px(70), color.RGBA{128, 128, 128, 255})
+
+	text.Draw(image, message, viz.fontMap["small"], textX, textY+viz.px(150),
+		color.RGBA{255, 128, 128, 255})
+}
+
+func (viz *playfieldViz) DrawOptionsToImage(image *ebiten.Image, names []string, values []string, cursor int,
+	preview colorSimulation) {
+	viz.drawSideBorders(image)
+
+	textX := viz.xOffset + viz.xBuffer
+	textY := viz.yOffset + viz.yPixelSize/3
+
+	text.Draw(image, "Options", viz.fontMap["base"], textX, textY-viz.px(40),
+		color.RGBA{128, 128, 128, 255})
+
+	for i := range names {
+		marker := "  "
+		if i == cursor {
+			marker = "> "
+		}
+		text.Draw(image, marker+names[i], viz.fontMap["small"], textX, textY+i*viz.px(20),
+			color.RGBA{128, 128, 128, 255})
+		text.Draw(image, values[i], viz.fontMap["small"], textX+viz.px(100), textY+i*viz.px(20),
+			color.RGBA{128, 128, 128, 255})
+	}
+
+	text.Draw(image, "Left/Right: change\nStart: done", viz.fontMap["small"],
+		textX, textY+len(names)*viz.px(20)+viz.px(20), color.RGBA{128, 128, 128, 255})
+
+	// how the player's pieces look, through the preview when one is picked
+	viz.drawColorPreview(image, textX, textY+len(names)*viz.px(20)+viz.px(60), preview)
+}
+
+func (viz *playfieldViz) DrawWaitingPlayerToImage(image *ebiten.Image, name string, playerLevel int,
+	speed match.PlayerSpeed, ready bool) {
+	viz.drawSideBorders(image)
+
+	text.Draw(image, name, viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3,
+		color.RGBA{128, 128, 128, 255})
+
+	text.Draw(image, fmt.Sprintf("Level: %d", playerLevel), viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+viz.px(30),
+		color.RGBA{128, 128, 128, 255})
+
+	text.Draw(image, "Speed: "+match.SpeedNames[speed], viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+viz.px(60),
 		color.RGBA{128, 128, 128, 255})
 
 	if !ready {
-		text.Draw(image, "Press Button\nWhen Ready", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+60,
+		text.Draw(image, "Press Button\nWhen Ready", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+viz.px(90),
+			color.RGBA{128, 128, 128, 255})
+		text.Draw(image, "Start: options", viz.fontMap["small"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+viz.px(160),
 			color.RGBA{128, 128, 128, 255})
 	} else {
-		text.Draw(image, "Ready!", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+60,
+		text.Draw(image, "Ready!", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3+viz.px(90),
 			color.RGBA{128, 128, 128, 255})
 	}
 }
 
 func (viz *playfieldViz) DrawResultToImage(image *ebiten.Image, isMatchWinner bool, isBigWinner bool) {
-	// draw left border
-	geom := ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset), float64(viz.yOffset))
-	pxImage := viz.imageMap["greenPixel"]
-	newImg := ebiten.NewImageFromImage(pxImage)
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
-
-	// draw right border
-	geom = ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset+viz.xBuffer+viz.xPixelSize), float64(viz.yOffset))
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+	viz.drawSideBorders(image)
 
 	if isBigWinner {
 		text.Draw(image, "Champ!", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3,
@@ -112,20 +202,40 @@ func (viz *playfieldViz) DrawResultToImage(image *ebiten.Image, isMatchWinner bo
 	}
 }
 
-func (viz *playfieldViz) DrawPausedToImage(image *ebiten.Image, isPauser bool) {
-	// draw left border
-	geom := ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset), float64(viz.yOffset))
-	pxImage := viz.imageMap["greenPixel"]
-	newImg := ebiten.NewImageFromImage(pxImage)
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+// match stats in the small font on the bottom third of the column
+func (viz *playfieldViz) DrawStatsToImage(image *ebiten.Image, stats match.PlayerStats) {
+	firstClear := "-"
+	if stats.TimeToFirstClear > 0 {
+		firstClear = fmt.Sprintf("%.1fs", stats.TimeToFirstClear.Seconds())
+	}
+	topOutRow := "-"
+	if stats.TopOutRow != nil {
+		topOutRow = fmt.Sprintf("%d", *stats.TopOutRow)
+	}
 
-	// draw right border
-	geom = ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset+viz.xBuffer+viz.xPixelSize), float64(viz.yOffset))
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+	lines := []string{
+		fmt.Sprintf("Pills: %d", stats.PillsPlaced),
+		fmt.Sprintf("Viruses: %d", stats.VirusesCleared),
+		fmt.Sprintf("Best chain: %d", stats.LongestChain),
+		fmt.Sprintf("Sent: %d", match.SumGarbage(stats.GarbageSent)),
+		fmt.Sprintf("Received: %d", match.SumGarbage(stats.GarbageReceived)),
+		"First clear: " + firstClear,
+		"Top row: " + topOutRow,
+		fmt.Sprintf("Avg place: %.1fs", stats.AveragePlacementTime.Seconds()),
+		fmt.Sprintf("Inputs/pill: %.1f", stats.InputsPerPill),
+	}
+
+	lineHeight := viz.px(16)
+	y := viz.yOffset + 2*viz.yPixelSize/3 - 2*lineHeight
+	for _, line := range lines {
+		text.Draw(image, line, viz.fontMap["small"], viz.xOffset+viz.xBuffer, y,
+			color.RGBA{128, 128, 128, 255})
+		y += lineHeight
+	}
+}
+
+func (viz *playfieldViz) DrawPausedToImage(image *ebiten.Image, isPauser bool) {
+	viz.drawSideBorders(image)
 
 	if isPauser {
 		text.Draw(image, "Paused", viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3,
@@ -133,99 +243,116 @@ func (viz *playfieldViz) DrawPausedToImage(image *ebiten.Image, isPauser bool) {
 	}
 }
 
+func (viz *playfieldViz) DrawControllerLostToImage(image *ebiten.Image, playerIndex int) {
+	viz.drawSideBorders(image)
+
+	textX := viz.xOffset + viz.xBuffer
+	textY := viz.yOffset + viz.yPixelSize/3
+
+	text.Draw(image, fmt.Sprintf("Player %d\ncontroller\nlost", playerIndex+1), viz.fontMap["base"], textX, textY,
+		color.RGBA{255, 128, 128, 255})
+	text.Draw(image, "Press start on a\nfree controller\nto take over", viz.fontMap["small"], textX, textY+viz.px(90),
+		color.RGBA{128, 128, 128, 255})
+}
+
 func (viz *playfieldViz) DrawBoardToImage(image *ebiten.Image) {
 	// no field means nothing to draw, just return
 	if viz.fieldState == nil {
 		return
 	}
 
-	// draw left border
-	geom := ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset), float64(viz.yOffset))
-	pxImage := viz.imageMap["greenPixel"]
-	newImg := ebiten.NewImageFromImage(pxImage)
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
-
-	// draw right border
-	geom = ebiten.GeoM{}
-	geom.Scale(float64(viz.xBuffer), float64(viz.yPixelSize))
-	geom.Translate(float64(viz.xOffset+viz.xBuffer+viz.xPixelSize), float64(viz.yOffset))
-	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+	viz.drawSideBorders(image)
+	newImg := viz.atlas.Sprite("border")
 
 	// draw field/status border
-	geom = ebiten.GeoM{}
-	geom.Scale(float64(2*viz.xBuffer+viz.xPixelSize), float64(viz.yBuffer))
+	geom := stretchedGeoM(newImg, float64(2*viz.xBuffer+viz.xPixelSize), float64(viz.yBuffer))
 	geom.Translate(float64(viz.xOffset), float64(viz.yOffset+viz.playfieldY))
 	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
 
 	// draw field bottom border
-	geom = ebiten.GeoM{}
-	geom.Scale(float64(2*viz.xBuffer+viz.xPixelSize), float64(viz.yBuffer))
+	geom = stretchedGeoM(newImg, float64(2*viz.xBuffer+viz.xPixelSize), float64(viz.yBuffer))
 	geom.Translate(float64(viz.xOffset), float64(viz.yOffset+viz.playfieldY+viz.yBuffer+viz.statusY))
 	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
 
 	// start block draw
-	yBlockPx := float64(viz.playfieldY) / float64(len(viz.fieldState))
-	xBlockPx := float64(viz.xPixelSize) / float64(len(viz.fieldState[0]))
+	xBlockPx, yBlockPx := viz.getBlockSize()
+
+	viz.drawGhost(image, xBlockPx, yBlockPx)
 
 	// draw blocks
 	for y, row := range viz.fieldState {
 		for x, space := range row {
 			// if we have an assigned image for the space, draw it in the block
 			if space.image != nil {
-				geom := ebiten.GeoM{}
-
-				// scale to size
-				imgX, imgY := space.image.Size()
-				geom.Scale(xBlockPx/float64(imgX), yBlockPx/float64(imgY))
-
-				if space.space.Linkage != drbreakboard.Unlinked {
-					geom.Translate(xBlockPx/-2, yBlockPx/-2)
-					switch space.space.Linkage {
-					case drbreakboard.Left:
-						geom.Rotate(math.Pi / 2)
-					case drbreakboard.Up:
-						geom.Rotate(math.Pi)
-					case drbreakboard.Right:
-						geom.Rotate(-math.Pi / 2)
-					}
-					geom.Translate(xBlockPx/2, yBlockPx/2)
+				// spaces still falling into place are drawn partway up
+				rowsUp, alpha := viz.spaceMotion(y, x)
+				left := float64(x)*xBlockPx + float64(viz.xOffset+viz.xBuffer)
+				top := (float64(y)-rowsUp)*yBlockPx + float64(viz.yOffset)
+				drawBoardSpace(image, space, xBlockPx, yBlockPx, left, top, alpha)
+			}
+		}
+	}
 
-				}
+	viz.drawClearAnimations(image, xBlockPx, yBlockPx)
+	viz.drawTopOut(image, yBlockPx)
+}
 
-				// move block to correct location
-				geom.Translate(float64(x)*xBlockPx+float64(viz.xOffset+viz.xBuffer), float64(y)*yBlockPx+float64(viz.yOffset))
+// a distance laid out for a 160 pixel column, scaled to this one
+func (viz *playfieldViz) px(distance int) int {
+	return int(float64(distance) * viz.scale)
+}
 
-				image.DrawImage(space.image, &ebiten.DrawImageOptions{GeoM: geom})
-			}
-		}
+// size of one board space in pixels
+func (viz *playfieldViz) getBlockSize() (float64, float64) {
+	rows, cols := match.BoardHeight, match.BoardWidth
+	if viz.fieldState != nil {
+		rows, cols = len(viz.fieldState), len(viz.fieldState[0])
 	}
+
+	return float64(viz.xPixelSize) / float64(cols), float64(viz.playfieldY) / float64(rows)
 }
 
-func drawPillSpace(space playfieldState, xBlockPx float64, yBlockPx float64, x int, y int, image *ebiten.Image) {
-	geom := ebiten.GeoM{}
+// outline the board space at y, x
+func (viz *playfieldViz) DrawCursorToImage(image *ebiten.Image, y int, x int) {
+	xBlockPx, yBlockPx := viz.getBlockSize()
+	left := float64(x)*xBlockPx + float64(viz.xOffset+viz.xBuffer)
+	top := float64(y)*yBlockPx + float64(viz.yOffset)
+	const lineWidth = 2
 
-	imgX, imgY := space.image.Size()
-	geom.Scale(xBlockPx/float64(imgX), yBlockPx/float64(imgY))
-
-	if space.space.Linkage != drbreakboard.Unlinked {
-		geom.Translate(xBlockPx/-2, yBlockPx/-2)
-		switch space.space.Linkage {
-		case drbreakboard.Left:
-			geom.Rotate(math.Pi / 2)
-		case drbreakboard.Up:
-			geom.Rotate(math.Pi)
-		case drbreakboard.Right:
-			geom.Rotate(-math.Pi / 2)
-		}
-		geom.Translate(xBlockPx/2, yBlockPx/2)
+	pxImage := viz.atlas.Sprite("border")
 
+	// top and bottom edges
+	for _, edgeY := range []float64{top, top + yBlockPx - lineWidth} {
+		geom := stretchedGeoM(pxImage, xBlockPx, lineWidth)
+		geom.Translate(left, edgeY)
+		image.DrawImage(pxImage, &ebiten.DrawImageOptions{GeoM: geom})
 	}
 
-	geom.Translate(float64(x), float64(y))
+	// left and right edges
+	for _, edgeX := range []float64{left, left + xBlockPx - lineWidth} {
+		geom := stretchedGeoM(pxImage, lineWidth, yBlockPx)
+		geom.Translate(edgeX, top)
+		image.DrawImage(pxImage, &ebiten.DrawImageOptions{GeoM: geom})
+	}
+}
 
-	image.DrawImage(space.image, &ebiten.DrawImageOptions{GeoM: geom})
+// draw a single space at pixel x, y outside of the board, e.g. an editor brush
+func (viz *playfieldViz) DrawSpaceToImage(image *ebiten.Image, space drbreakboard.Space, x int, y int, size float64) {
+	var spaceImage *ebiten.Image
+	switch space.Content {
+	case drbreakboard.Virus:
+		spaceImage = viz.getVirusImage(space)
+	case drbreakboard.Pill:
+		spaceImage, _ = viz.getPillImage(space)
+	default:
+		return
+	}
+
+	drawPillSpace(playfieldState{space: space, image: spaceImage}, size, size, x, y, image)
+}
+
+func drawPillSpace(space playfieldState, xBlockPx float64, yBlockPx float64, x int, y int, image *ebiten.Image) {
+	drawBoardSpace(image, space, xBlockPx, yBlockPx, float64(x), float64(y), 1)
 }
 
 func (viz *playfieldViz) DrawStatusToImage(image *ebiten.Image, virusCount int,
@@ -237,9 +364,75 @@ func (viz *playfieldViz) DrawStatusToImage(image *ebiten.Image, virusCount int,
 		viz.xOffset+viz.xBuffer, viz.yOffset+viz.playfieldY+viz.yBuffer+firstWordY,
 		color.RGBA{128, 128, 128, 255})
 
+	nextY := viz.yOffset + viz.playfieldY + viz.yBuffer + firstWordY + viz.px(30)
+	viz.drawNextPill(image, nextY, nextPill)
+
+	// draw drop warning if needed
+	if hasDrops {
+		text.Draw(image, "DROPSICLE!", viz.fontMap["base"],
+			viz.xOffset+viz.xBuffer, nextY+viz.px(30),
+			color.RGBA{255, 128, 128, 255})
+	}
+}
+
+// status for survival mode, score and timers replace the virus count
+func (viz *playfieldViz) DrawSurvivalStatusToImage(image *ebiten.Image, score int,
+	elapsed time.Duration, untilRise time.Duration, nextPill [2]drbreakboard.Space) {
+	scoreText := fmt.Sprintf("Score: %d", score)
+	firstWordY := text.BoundString(viz.fontMap["base"], scoreText).Dy()
+	textX := viz.xOffset + viz.xBuffer
+	textY := viz.yOffset + viz.playfieldY + viz.yBuffer + firstWordY
+
+	text.Draw(image, scoreText, viz.fontMap["base"], textX, textY,
+		color.RGBA{128, 128, 128, 255})
+
+	text.Draw(image, "Time: "+formatClock(elapsed), viz.fontMap["base"], textX, textY+viz.px(30),
+		color.RGBA{128, 128, 128, 255})
+
+	// rise countdown goes red when a row is about to come up
+	riseColor := color.RGBA{128, 128, 128, 255}
+	if untilRise < 2*time.Second {
+		riseColor = color.RGBA{255, 128, 128, 255}
+	}
+	text.Draw(image, fmt.Sprintf("Rise: %.1f", untilRise.Seconds()), viz.fontMap["base"], textX, textY+viz.px(60),
+		riseColor)
+
+	viz.drawNextPill(image, textY+viz.px(90), nextPill)
+}
+
+func (viz *playfieldViz) DrawSurvivalResultToImage(image *ebiten.Image, score int, elapsed time.Duration) {
+	viz.drawSideBorders(image)
+
+	text.Draw(image, fmt.Sprintf("Game\nOver!\n\nScore:\n%d\n\nTime:\n%s", score, formatClock(elapsed)),
+		viz.fontMap["base"], viz.xOffset+viz.xBuffer, viz.yOffset+viz.yPixelSize/3,
+		color.RGBA{128, 128, 128, 255})
+}
+
+func (viz *playfieldViz) drawSideBorders(image *ebiten.Image) {
+	// draw left border
+	newImg := viz.atlas.Sprite("border")
+	geom := stretchedGeoM(newImg, float64(viz.xBuffer), float64(viz.yPixelSize))
+	geom.Translate(float64(viz.xOffset), float64(viz.yOffset))
+	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+
+	// draw right border
+	geom = stretchedGeoM(newImg, float64(viz.xBuffer), float64(viz.yPixelSize))
+	geom.Translate(float64(viz.xOffset+viz.xBuffer+viz.xPixelSize), float64(viz.yOffset))
+	image.DrawImage(newImg, &ebiten.DrawImageOptions{GeoM: geom})
+}
+
+// a scale to stretch sprite over width by height pixels, themes can bring sprites of any size
+func stretchedGeoM(sprite *ebiten.Image, width float64, height float64) ebiten.GeoM {
+	spriteWidth, spriteHeight := sprite.Size()
+	geom := ebiten.GeoM{}
+	geom.Scale(width/float64(spriteWidth), height/float64(spriteHeight))
+	return geom
+}
+
+// draw the "Next:" label with the next pill beside it, nextY is the text baseline
+func (viz *playfieldViz) drawNextPill(image *ebiten.Image, nextY int, nextPill [2]drbreakboard.Space) {
 	nextBoundRect := text.BoundString(viz.fontMap["base"], "Next:")
 	nextX := viz.xOffset + viz.xBuffer
-	nextY := viz.yOffset + viz.playfieldY + viz.yBuffer + firstWordY + 30
 	text.Draw(image, "Next:", viz.fontMap["base"],
 		nextX, nextY,
 		color.RGBA{128, 128, 128, 255})
@@ -257,15 +450,14 @@ func (viz *playfieldViz) DrawStatusToImage(image *ebiten.Image, virusCount int,
 	}
 
 	// draw next
-	drawPillSpace(viz.nextPillState[0], 20, 20, nextX+nextBoundRect.Dx(), nextY-nextBoundRect.Dy(), image)
-	drawPillSpace(viz.nextPillState[1], 20, 20, nextX+nextBoundRect.Dx()+20, nextY-nextBoundRect.Dy()-1, image)
+	size := float64(viz.px(20))
+	drawPillSpace(viz.nextPillState[0], size, size, nextX+nextBoundRect.Dx(), nextY-nextBoundRect.Dy(), image)
+	drawPillSpace(viz.nextPillState[1], size, size, nextX+nextBoundRect.Dx()+viz.px(20), nextY-nextBoundRect.Dy()-1, image)
+}
 
-	// draw drop warning if needed
-	if hasDrops {
-		text.Draw(image, "DROPSICLE!", viz.fontMap["base"],
-			viz.xOffset+viz.xBuffer, nextY+30,
-			color.RGBA{255, 128, 128, 255})
-	}
+func formatClock(d time.Duration) string {
+	totalSeconds := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", totalSeconds/60, totalSeconds%60)
 }
 
 func (viz *playfieldViz) UpdateBoard(playfield *drbreakboard.PlayField,
@@ -294,16 +486,7 @@ func (viz *playfieldViz) UpdateBoard(playfield *drbreakboard.PlayField,
 				var err error
 
 				if space.Content == drbreakboard.Virus {
-					switch space.Color {
-					case drbreakboard.Blue:
-						newImg = ebiten.NewImageFromImage(viz.imageMap["blueVirus"])
-					case drbreakboard.Red:
-						newImg = ebiten.NewImageFromImage(viz.imageMap["redVirus"])
-					case drbreakboard.Yellow:
-						newImg = ebiten.NewImageFromImage(viz.imageMap["yellowVirus"])
-					default:
-						panic("virus had bad color")
-					}
+					newImg = viz.getVirusImage(space)
 				}
 
 				if space.Content == drbreakboard.Pill {
@@ -333,6 +516,52 @@ func (viz *playfieldViz) UpdateBoard(playfield *drbreakboard.PlayField,
 	}
 }
 
+// show the active pill at its landing spot, or hide it when visible is false
+func (viz *playfieldViz) UpdateGhost(activePill [2]drbreakboard.Space, location [2]int, visible bool) {
+	viz.showGhost = visible && activePill[0].Content != drbreakboard.Empty
+	if !viz.showGhost {
+		return
+	}
+
+	viz.ghostPosition = location
+	for i := range activePill {
+		if viz.ghostState[i].space != activePill[i] || viz.ghostState[i].image == nil {
+			viz.ghostState[i].space = activePill[i]
+			viz.ghostState[i].image, _ = viz.getPillImage(activePill[i])
+		}
+	}
+}
+
+// the ghost goes under the board so the real pill covers it once it lands
+func (viz *playfieldViz) drawGhost(image *ebiten.Image, xBlockPx float64, yBlockPx float64) {
+	const ghostAlpha = 0.3
+	if !viz.showGhost || viz.ghostState[0].image == nil || viz.ghostState[1].image == nil {
+		return
+	}
+
+	linkedY, linkedX, _ := drbreakboard.GetLinkedCoordinate(viz.ghostPosition[0], viz.ghostPosition[1],
+		viz.ghostState[0].space.Linkage)
+	positions := [2][2]int{viz.ghostPosition, {linkedY, linkedX}}
+	for i, position := range positions {
+		left := float64(position[1])*xBlockPx + float64(viz.xOffset+viz.xBuffer)
+		top := float64(position[0])*yBlockPx + float64(viz.yOffset)
+		drawBoardSpace(image, viz.ghostState[i], xBlockPx, yBlockPx, left, top, ghostAlpha)
+	}
+}
+
+func (viz *playfieldViz) getVirusImage(space drbreakboard.Space) *ebiten.Image {
+	switch space.Color {
+	case drbreakboard.Blue:
+		return viz.styledSprite("blueVirus")
+	case drbreakboard.Red:
+		return viz.styledSprite("redVirus")
+	case drbreakboard.Yellow:
+		return viz.styledSprite("yellowVirus")
+	default:
+		panic("virus had bad color")
+	}
+}
+
 func (viz *playfieldViz) getPillImage(space drbreakboard.Space) (*ebiten.Image, error) {
 	var newImg *ebiten.Image
 	var err error
@@ -340,22 +569,22 @@ func (viz *playfieldViz) getPillImage(space drbreakboard.Space) (*ebiten.Image,
 	if space.Linkage == drbreakboard.Unlinked {
 		switch space.Color {
 		case drbreakboard.Blue:
-			newImg = ebiten.NewImageFromImage(viz.imageMap["blueSingle"])
+			newImg = viz.styledSprite("blueSingle")
 		case drbreakboard.Red:
-			newImg = ebiten.NewImageFromImage(viz.imageMap["redSingle"])
+			newImg = viz.styledSprite("redSingle")
 		case drbreakboard.Yellow:
-			newImg = ebiten.NewImageFromImage(viz.imageMap["yellowSingle"])
+			newImg = viz.styledSprite("yellowSingle")
 		default:
 			panic("pill had bad color")
 		}
 	} else {
 		switch space.Color {
 		case drbreakboard.Blue:
-			newImg = ebiten.NewImageFromImage(viz.imageMap["blueLinked"])
+			newImg = viz.styledSprite("blueLinked")
 		case drbreakboard.Red:
-			newImg = ebiten.NewImageFromImage(viz.imageMap["redLinked"])
+			newImg = viz.styledSprite("redLinked")
 		case drbreakboard.Yellow:
-			newImg = ebiten.NewImageFromImage(viz.imageMap["yellowLinked"])
+			newImg = viz.styledSprite("yellowLinked")
 		default:
 			panic("pill had bad color")
 		}
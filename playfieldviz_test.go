@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// device ids for the benchmark's fake players, well clear of real devices
+const benchControllerId = 31000
+
+// mashes random moves, sometimes holding down, so boards fill and clear
+type randomSource struct {
+	deviceId int
+	rand     *rand.Rand
+	held     [match.InputActionCount]bool
+}
+
+func NewRandomSource(deviceId int, seed int64) *randomSource {
+	rs := &randomSource{deviceId: deviceId, rand: rand.New(rand.NewSource(seed))}
+	return rs
+}
+
+func (rs *randomSource) DeviceId() int {
+	return rs.deviceId
+}
+
+func (rs *randomSource) Kind() match.DeviceKind {
+	return match.CPUDevice
+}
+
+func (rs *randomSource) Poll(tick int) []match.InputEvent {
+	pressed := [match.InputActionCount]bool{}
+	moves := [...]match.InputAction{match.ActionLeft, match.ActionRight, match.ActionPrimary, match.ActionSecondary, match.ActionDown}
+	if rs.rand.Intn(4) == 0 {
+		pressed[moves[rs.rand.Intn(len(moves))]] = true
+	}
+	return match.ActionsToInputEvents(rs, tick, pressed, &rs.held)
+}
+
+// one frame of a four player match with random inputs, drawn through each player's playfieldViz
+func BenchmarkDraw4Players(b *testing.B) {
+	game, err := NewGame("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		controllerId := benchControllerId + i
+		game.inputDriver.AddSource(NewRandomSource(controllerId, int64(i)))
+		game.addPlayer(controllerId)
+	}
+	game.currentStage = MatchRunning
+
+	game.Layout(baseScreenWidth, baseScreenHeight)
+	screen := ebiten.NewImage(baseScreenWidth, baseScreenHeight)
+	defer screen.Dispose()
+
+	// a second of play first so startup and first uploads don't count
+	for i := 0; i < 60; i++ {
+		if err := game.Update(); err != nil {
+			b.Fatal(err)
+		}
+		game.Draw(screen)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// keep playing through top outs so every frame is a match frame
+		if game.currentStage == MatchEnded {
+			game.matchDriver.ResetAndStartMatch()
+			for _, pv := range game.playfieldViz {
+				pv.ClearAnimations()
+			}
+			game.currentStage = MatchRunning
+		}
+
+		if err := game.Update(); err != nil {
+			b.Fatal(err)
+		}
+		game.Draw(screen)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/drbreaktime/match"
+)
+
+const profileFileName = "profiles.json"
+
+// bump when the file layout changes and add a step to migrateProfileFile
+const profileFileVersion = 3
+
+const maxProfileNameLength = 8
+
+type playerProfile struct {
+	Name           string            `json:"name"`
+	PreferredLevel int               `json:"preferredLevel"`
+	PreferredSpeed match.PlayerSpeed `json:"preferredSpeed"`
+
+	Options playerOptions `json:"options"`
+
+	// bindings by action name by device, used over the shared ones while this profile plays
+	// devices are named the way the bindings file names them, e.g. keyboard2 or a pad's SDL id
+	Bindings map[string]map[string][]string `json:"bindings,omitempty"`
+
+	Lifetime lifetimeStats `json:"lifetime"`
+
+	Rating       float64 `json:"rating"`
+	RatedMatches int     `json:"ratedMatches"`
+}
+
+// totals over every match the profile has played
+type lifetimeStats struct {
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+
+	// fastest board clear at each level
+	BestClearTimes map[int]time.Duration `json:"bestClearTimes"`
+
+	BestSurvivalScore int           `json:"bestSurvivalScore"`
+	BestSurvivalTime  time.Duration `json:"bestSurvivalTime"`
+
+	PillsPlaced     int `json:"pillsPlaced"`
+	VirusesCleared  int `json:"virusesCleared"`
+	LongestChain    int `json:"longestChain"`
+	GarbageSent     int `json:"garbageSent"`
+	GarbageReceived int `json:"garbageReceived"`
+}
+
+// on disk layout of the profile file
+type profileFile struct {
+	Version        int              `json:"version"`
+	Profiles       []*playerProfile `json:"profiles"`
+	RatingsEnabled bool             `json:"ratingsEnabled"`
+	RatingHistory  []ratingRecord   `json:"ratingHistory"`
+}
+
+type profileStore struct {
+	filePath       string
+	profiles       []*playerProfile
+	ratingsEnabled bool
+	ratingHistory  []ratingRecord
+}
+
+func NewProfileStore(filePath string) *profileStore {
+	store := &profileStore{filePath: filePath}
+	store.profiles = make([]*playerProfile, 0)
+	store.ratingHistory = make([]ratingRecord, 0)
+	return store
+}
+
+// load profiles from disk, a missing file is an empty store
+func (store *profileStore) Load() error {
+	data, err := os.ReadFile(store.filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		store.profiles = make([]*playerProfile, 0)
+		store.ratingHistory = make([]ratingRecord, 0)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pf := &profileFile{}
+	if err := json.Unmarshal(data, pf); err != nil {
+		return fmt.Errorf("%s: %w", store.filePath, err)
+	}
+
+	if err := migrateProfileFile(pf); err != nil {
+		return fmt.Errorf("%s: %w", store.filePath, err)
+	}
+
+	store.profiles = pf.Profiles
+	store.ratingsEnabled = pf.RatingsEnabled
+	store.ratingHistory = pf.RatingHistory
+	for _, profile := range store.profiles {
+		if profile.Lifetime.BestClearTimes == nil {
+			profile.Lifetime.BestClearTimes = make(map[int]time.Duration)
+		}
+		profile.Options.AutoRepeat.Clamp()
+	}
+
+	return nil
+}
+
+func (store *profileStore) Save() error {
+	if store.filePath == "" {
+		return errors.New("no profile file path")
+	}
+
+	data, err := json.MarshalIndent(profileFile{
+		Version:        profileFileVersion,
+		Profiles:       store.profiles,
+		RatingsEnabled: store.ratingsEnabled,
+		RatingHistory:  store.ratingHistory,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(store.filePath, data)
+}
+
+// bring an older profile file up to the current version
+func migrateProfileFile(pf *profileFile) error {
+	if pf.Version > profileFileVersion {
+		return fmt.Errorf("profile file version %d is newer than this game", pf.Version)
+	}
+
+	// files written before versioning have no version field
+	if pf.Version == 0 {
+		pf.Version = 1
+	}
+
+	if pf.Profiles == nil {
+		pf.Profiles = make([]*playerProfile, 0)
+	}
+
+	// version 2 added ratings, everyone starts at the initial rating
+	if pf.Version < 2 {
+		for _, profile := range pf.Profiles {
+			profile.Rating = initialRating
+		}
+		pf.Version = 2
+	}
+
+	// version 3 added player options
+	if pf.Version < 3 {
+		for _, profile := range pf.Profiles {
+			profile.Options = defaultPlayerOptions()
+		}
+		pf.Version = 3
+	}
+
+	if pf.RatingHistory == nil {
+		pf.RatingHistory = make([]ratingRecord, 0)
+	}
+
+	return nil
+}
+
+func (store *profileStore) Get(name string) *playerProfile {
+	for _, profile := range store.profiles {
+		if profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+func (store *profileStore) Names() []string {
+	names := make([]string, len(store.profiles))
+	for i, profile := range store.profiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+func (store *profileStore) Create(name string) (*playerProfile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("profile needs a name")
+	}
+
+	if len(name) > maxProfileNameLength {
+		return nil, errors.New("profile name too long")
+	}
+
+	if store.Get(name) != nil {
+		return nil, errors.New("profile name taken")
+	}
+
+	profile := &playerProfile{
+		Name:           name,
+		PreferredLevel: 10,
+		PreferredSpeed: match.SpeedMed,
+		Options:        defaultPlayerOptions(),
+		Rating:         initialRating,
+	}
+	profile.Lifetime.BestClearTimes = make(map[int]time.Duration)
+
+	store.profiles = append(store.profiles, profile)
+
+	return profile, nil
+}
+
+// add a finished match to the profile's lifetime stats
+func (profile *playerProfile) RecordMatch(mode match.MatchMode, won bool, clearTime time.Duration, level int,
+	score int, survived time.Duration, stats match.PlayerStats) {
+	lifetime := &profile.Lifetime
+
+	if mode == match.SurvivalMode {
+		if score > lifetime.BestSurvivalScore {
+			lifetime.BestSurvivalScore = score
+		}
+		if survived > lifetime.BestSurvivalTime {
+			lifetime.BestSurvivalTime = survived
+		}
+	} else if won {
+		lifetime.Wins++
+
+		// clearTime is zero when the win came from everyone else topping out
+		best, exists := lifetime.BestClearTimes[level]
+		if clearTime > 0 && (!exists || clearTime < best) {
+			lifetime.BestClearTimes[level] = clearTime
+		}
+	} else {
+		lifetime.Losses++
+	}
+
+	lifetime.PillsPlaced += stats.PillsPlaced
+	lifetime.VirusesCleared += stats.VirusesCleared
+	if stats.LongestChain > lifetime.LongestChain {
+		lifetime.LongestChain = stats.LongestChain
+	}
+	lifetime.GarbageSent += match.SumGarbage(stats.GarbageSent)
+	lifetime.GarbageReceived += match.SumGarbage(stats.GarbageReceived)
+}
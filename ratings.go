@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+const initialRating = 1500.0
+const ratingK = 32.0 // most a rating can move in one two player match
+const ratingsCSVFileName = "ratings.csv"
+
+// one player's rating change from one match
+type ratingRecord struct {
+	Time      time.Time `json:"time"`
+	Player    string    `json:"player"`
+	Placement int       `json:"placement"`
+	Players   int       `json:"players"`
+	Before    float64   `json:"before"`
+	After     float64   `json:"after"`
+}
+
+// elo change for every player from their placements, 1 is first
+// free for alls are scored as a two player match against every opponent,
+// with K split between the opponents so a match moves ratings as much as a 1v1
+func computeRatingChanges(ratings map[string]float64, placements map[string]int) map[string]float64 {
+	changes := make(map[string]float64)
+	if len(placements) < 2 {
+		return changes
+	}
+
+	opponentK := ratingK / float64(len(placements)-1)
+	for player, placement := range placements {
+		change := 0.0
+		for opponent, opponentPlacement := range placements {
+			if opponent == player {
+				continue
+			}
+
+			expected := 1 / (1 + math.Pow(10, (ratings[opponent]-ratings[player])/400))
+
+			actual := 0.5
+			if placement < opponentPlacement {
+				actual = 1
+			} else if placement > opponentPlacement {
+				actual = 0
+			}
+
+			change += opponentK * (actual - expected)
+		}
+		changes[player] = change
+	}
+
+	return changes
+}
+
+// update ratings for the profiles in a finished match
+// placements are keyed by profile name, unknown names are ignored
+func (store *profileStore) ApplyRatings(placements map[string]int, when time.Time) []ratingRecord {
+	ratings := make(map[string]float64)
+	known := make(map[string]int)
+	for name, placement := range placements {
+		profile := store.Get(name)
+		if profile == nil {
+			continue
+		}
+		ratings[name] = profile.Rating
+		known[name] = placement
+	}
+
+	changes := computeRatingChanges(ratings, known)
+
+	// walk profiles in store order so the history order is stable
+	records := make([]ratingRecord, 0)
+	for _, profile := range store.profiles {
+		change, rated := changes[profile.Name]
+		if !rated {
+			continue
+		}
+
+		record := ratingRecord{
+			Time:      when,
+			Player:    profile.Name,
+			Placement: known[profile.Name],
+			Players:   len(known),
+			Before:    profile.Rating,
+			After:     profile.Rating + change,
+		}
+
+		profile.Rating = record.After
+		profile.RatedMatches++
+		records = append(records, record)
+	}
+
+	store.ratingHistory = append(store.ratingHistory, records...)
+
+	return records
+}
+
+// profiles best rating first, profiles with no rated matches go last
+func (store *profileStore) Leaderboard() []*playerProfile {
+	board := make([]*playerProfile, len(store.profiles))
+	copy(board, store.profiles)
+
+	sort.SliceStable(board, func(i, j int) bool {
+		if (board[i].RatedMatches == 0) != (board[j].RatedMatches == 0) {
+			return board[i].RatedMatches > 0
+		}
+		return board[i].Rating > board[j].Rating
+	})
+
+	return board
+}
+
+func writeRatingsCSV(w io.Writer, history []ratingRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "player", "placement", "players", "before", "after", "change"})
+
+	for _, record := range history {
+		cw.Write([]string{
+			record.Time.Format(time.RFC3339),
+			record.Player,
+			fmt.Sprint(record.Placement),
+			fmt.Sprint(record.Players),
+			fmt.Sprintf("%.1f", record.Before),
+			fmt.Sprintf("%.1f", record.After),
+			fmt.Sprintf("%+.1f", record.After-record.Before),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (store *profileStore) ExportRatingsCSV(filePath string) error {
+	var buf bytes.Buffer
+	if err := writeRatingsCSV(&buf, store.ratingHistory); err != nil {
+		return err
+	}
+	return writeFileAtomic(filePath, buf.Bytes())
+}
+
+func ratingsCSVFilePath() (string, error) {
+	return configFilePath(ratingsCSVFileName)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeRatingChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		ratings    map[string]float64
+		placements map[string]int
+		want       map[string]float64
+	}{
+		{
+			"two players",
+			map[string]float64{"a": 1500, "b": 1500},
+			map[string]int{"a": 1, "b": 2},
+			map[string]float64{"a": 16, "b": -16},
+		},
+		{
+			// K is split over two opponents, so winning both moves as much as a 1v1 win
+			"three players",
+			map[string]float64{"a": 1500, "b": 1500, "c": 1500},
+			map[string]int{"a": 1, "b": 2, "c": 3},
+			map[string]float64{"a": 16, "b": 0, "c": -16},
+		},
+		{
+			"three players tied for first",
+			map[string]float64{"a": 1500, "b": 1500, "c": 1500},
+			map[string]int{"a": 1, "b": 1, "c": 3},
+			map[string]float64{"a": 8, "b": 8, "c": -16},
+		},
+		{
+			"four players",
+			map[string]float64{"a": 1500, "b": 1500, "c": 1500, "d": 1500},
+			map[string]int{"a": 1, "b": 2, "c": 3, "d": 4},
+			map[string]float64{"a": 16, "b": 16.0 / 3, "c": -16.0 / 3, "d": -16},
+		},
+		{
+			// a 400 point favourite is expected to win 10 to 1
+			"four players with an upset",
+			map[string]float64{"a": 1500, "b": 1500, "c": 1500, "d": 1900},
+			map[string]int{"a": 1, "b": 2, "c": 3, "d": 4},
+			map[string]float64{
+				"a": 32.0 / 3 * (0.5 + 0.5 + 10.0/11),
+				"b": 32.0 / 3 * (-0.5 + 0.5 + 10.0/11),
+				"c": 32.0 / 3 * (-0.5 - 0.5 + 10.0/11),
+				"d": 32.0 / 3 * 3 * (-10.0 / 11),
+			},
+		},
+		{
+			"one player",
+			map[string]float64{"a": 1500},
+			map[string]int{"a": 1},
+			map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := computeRatingChanges(tt.ratings, tt.placements)
+			if len(changes) != len(tt.want) {
+				t.Fatalf("changes for %d players, want %d", len(changes), len(tt.want))
+			}
+
+			total := 0.0
+			for player, want := range tt.want {
+				if math.Abs(changes[player]-want) > 1e-9 {
+					t.Errorf("%s changed by %f, want %f", player, changes[player], want)
+				}
+				total += changes[player]
+			}
+
+			// every point won is one an opponent lost
+			if math.Abs(total) > 1e-9 {
+				t.Errorf("changes add up to %f, want 0", total)
+			}
+		})
+	}
+}
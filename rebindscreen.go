@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// menu rows after the actions
+const deadzoneStep = 0.05
+
+const (
+	rebindDeadzoneItem = int(match.InputActionCount) + iota
+	rebindDefaultsItem
+	rebindSaveItem
+	rebindItemCount
+)
+
+// rebinding one controller, edits only take effect when saved
+// so the menu keeps working on the old bindings while they change
+type rebindScreen struct {
+	controllerId int
+	bindings     deviceBindings
+	deadzone     float64
+	cursor       int
+	capturing    bool
+	appending    bool // add the captured binding instead of replacing the action's bindings
+	message      string
+}
+
+func NewRebindScreen(controllerId int, bindings deviceBindings, deadzone float64) *rebindScreen {
+	rs := &rebindScreen{controllerId: controllerId, bindings: bindings.clone(), deadzone: deadzone}
+	return rs
+}
+
+func (g *Game) updateRebind(buttonPressEvents map[int][]match.GamepadEvent) {
+	rs := g.rebindScreen
+
+	if rs.capturing {
+		b, captured := g.inputDriver.CaptureBinding(rs.controllerId)
+		if captured {
+			action := match.InputAction(rs.cursor)
+			if rs.appending {
+				rs.bindings[action] = append(rs.bindings[action], b)
+			} else {
+				rs.bindings[action] = []inputBinding{b}
+			}
+			rs.capturing = false
+			rs.message = g.keyboardConflict(b)
+		}
+		// the press being captured shouldn't also drive the menu
+		return
+	}
+
+	for _, event := range buttonPressEvents[rs.controllerId] {
+		switch event {
+		case match.UpJustPressed:
+			rs.cursor = (rs.cursor + rebindItemCount - 1) % rebindItemCount
+		case match.DownJustPressed:
+			rs.cursor = (rs.cursor + 1) % rebindItemCount
+		case match.LeftJustPressed, match.RightJustPressed:
+			if rs.cursor != rebindDeadzoneItem || g.inputDriver.IsKeyboardController(rs.controllerId) {
+				continue
+			}
+			step := deadzoneStep
+			if event == match.LeftJustPressed {
+				step = -deadzoneStep
+			}
+			rs.deadzone = math.Max(minStickDeadzone, math.Min(maxStickDeadzone, rs.deadzone+step))
+		case match.PrimaryJustPressed, match.SecondaryJustPressed:
+			switch rs.cursor {
+			case rebindDeadzoneItem:
+				// changed with left/right
+			case rebindDefaultsItem:
+				rs.bindings = g.inputDriver.DefaultBindings(rs.controllerId)
+				rs.deadzone = defaultStickDeadzone
+				rs.message = "Defaults restored, save to keep them"
+			case rebindSaveItem:
+				if err := g.saveRebind(); err != nil {
+					rs.message = err.Error()
+					continue
+				}
+				g.ResetGame()
+				return
+			default:
+				rs.capturing = true
+				rs.appending = event == match.SecondaryJustPressed
+				rs.message = "Press something for " + match.InputActionNames[rs.cursor]
+				g.inputDriver.StartCapture(rs.controllerId)
+				return
+			}
+		case match.SelectJustPressed:
+			// leave without saving
+			g.ResetGame()
+			return
+		}
+	}
+}
+
+// keys held by two keyboard controllers press both, so point it out
+func (g *Game) keyboardConflict(b inputBinding) string {
+	rs := g.rebindScreen
+	if b.kind != keyBinding {
+		return ""
+	}
+
+	for other := keyboardControllerId; g.inputDriver.IsKeyboardController(other); other++ {
+		if other == rs.controllerId {
+			continue
+		}
+		for _, list := range g.inputDriver.GetBindings(other) {
+			for _, otherBinding := range list {
+				if otherBinding == b {
+					return fmt.Sprintf("%s is also used by keyboard %d", b, other-keyboardControllerId+1)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (g *Game) saveRebind() error {
+	rs := g.rebindScreen
+
+	// an unbound action could leave the controller unable to get back here
+	for action := match.InputAction(0); action < match.InputActionCount; action++ {
+		if len(rs.bindings[action]) == 0 {
+			return fmt.Errorf("%s needs a binding", match.InputActionNames[action])
+		}
+	}
+
+	g.inputDriver.SetBindings(rs.controllerId, rs.bindings)
+	if !g.inputDriver.IsKeyboardController(rs.controllerId) {
+		g.inputDriver.SetDeadzone(rs.controllerId, rs.deadzone)
+	}
+	return g.inputDriver.SaveBindings()
+}
+
+func (g *Game) drawRebind(screen *ebiten.Image) {
+	textColor := color.RGBA{128, 128, 128, 255}
+	rs := g.rebindScreen
+
+	device := ebiten.GamepadName(ebiten.GamepadID(rs.controllerId))
+	if g.inputDriver.IsKeyboardController(rs.controllerId) {
+		device = fmt.Sprintf("Keyboard %d", rs.controllerId-keyboardControllerId+1)
+	}
+	text.Draw(screen, "Controls - "+device, BaseTextFont, 60, 50, textColor)
+	text.Draw(screen, "A: replace   B: add another   Select: cancel", SmallTextFont, 60, 80, textColor)
+
+	for i := 0; i < rebindItemCount; i++ {
+		marker := "  "
+		if i == rs.cursor {
+			marker = "> "
+		}
+
+		y := 130 + i*30
+		switch i {
+		case rebindDeadzoneItem:
+			deadzone := "-"
+			if !g.inputDriver.IsKeyboardController(rs.controllerId) {
+				deadzone = fmt.Sprintf("< %.2f >", rs.deadzone)
+			}
+			text.Draw(screen, marker+"Deadzone", BaseTextFont, 60, y, textColor)
+			text.Draw(screen, deadzone, SmallTextFont, 260, y, textColor)
+		case rebindDefaultsItem:
+			text.Draw(screen, marker+"Defaults", BaseTextFont, 60, y, textColor)
+		case rebindSaveItem:
+			text.Draw(screen, marker+"Save", BaseTextFont, 60, y, textColor)
+		default:
+			text.Draw(screen, marker+strings.ToUpper(match.InputActionNames[i][:1])+match.InputActionNames[i][1:], BaseTextFont, 60, y, textColor)
+			text.Draw(screen, describeBindings(rs.bindings[match.InputAction(i)]), SmallTextFont, 260, y, textColor)
+		}
+	}
+
+	text.Draw(screen, rs.message, SmallTextFont, 60, 460, textColor)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"example.com/drbreaktime/match"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// with -record, finished matches are saved here as replays, one file each
+const replaysDirName = "replays"
+
+// hand this tick's input events to the match's recorder, starting one on the match's first tick
+// call before the inputs are applied so the events land on the tick they play on
+func (g *Game) recordMatchInputs() {
+	if !g.recordReplays {
+		return
+	}
+
+	if g.recorder == nil {
+		autoRepeat := make([]match.AutoRepeatSettings, g.playerCount)
+		for playerIndex := range autoRepeat {
+			autoRepeat[playerIndex] = g.getPlayerOptions(playerIndex).AutoRepeat
+		}
+		g.recorder = match.NewReplayRecorder(g.matchDriver, autoRepeat)
+	}
+
+	playerIndexes := map[int]int{}
+	for playerIndex, controllerId := range g.controllerAssignments {
+		playerIndexes[controllerId] = playerIndex
+	}
+
+	playerEvents := map[int][]match.InputEvent{}
+	for _, event := range g.inputDriver.LastEvents() {
+		if playerIndex, assigned := playerIndexes[event.DeviceId]; assigned {
+			playerEvents[playerIndex] = append(playerEvents[playerIndex], event)
+		}
+	}
+
+	for playerIndex := 0; playerIndex < g.playerCount; playerIndex++ {
+		g.recorder.Record(playerIndex, g.matchDriver.MatchTicks, playerEvents[playerIndex])
+	}
+}
+
+// write the finished match's replay, with everyone's stats, to the replays folder
+func (g *Game) saveReplay() {
+	if g.recorder == nil {
+		return
+	}
+	rf := g.recorder.Replay(g.matchDriver)
+	g.recorder = nil
+
+	filePath, err := configFilePath(filepath.Join(replaysDirName, time.Now().Format("2006-01-02-150405")+".json"))
+	if err != nil {
+		zlog.Printf("could not save replay: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		zlog.Printf("could not save replay: %v", err)
+		return
+	}
+	if err := match.SaveReplayFile(filePath, rf); err != nil {
+		zlog.Printf("could not save replay: %v", err)
+		return
+	}
+	zlog.Printf("replay saved to %s", filePath)
+}
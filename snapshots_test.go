@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/drbreakboard"
+	"example.com/drbreaktime/assets"
+	"example.com/drbreaktime/boardrender"
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// go test -run TestSnapshots [-update]
+//
+// draws the playfield screens offscreen with fixed boards and compares them
+// against the golden pngs in testdata/golden, -update rewrites the goldens
+
+var updateGoldens = flag.Bool("update", false, "write the snapshot goldens from what's drawn now")
+
+// one playfield column at the base size
+const snapshotWidth = basePlayfieldWidth
+const snapshotHeight = baseScreenHeight
+
+const goldenDir = "testdata/golden"
+
+// where the actual and diff images of failures go
+const snapshotFailDir = "snapshot-failures"
+
+// largest difference in any channel that still counts as the same pixel
+// and the fraction of pixels allowed to differ, gpus and drivers draw a little differently
+const snapshotTolerance = 8
+const snapshotMaxDiff = 0.001
+
+// a board with a bit of everything, viruses and pills linked every way
+var snapshotBoardText = strings.Repeat("................\n", 10) + `
+........R*......
+....B*..........
+..Yv....B>Y<....
+..B^....R*......
+..R*Y*........B*
+R*B*Y*..Ro..R*Y*
+`
+
+// one screen to check, drawn into a fresh viz the size of a base column
+type snapshotCase struct {
+	name string
+	draw func(viz *playfieldViz, target *ebiten.Image)
+}
+
+func snapshotCases(bf *match.BoardFile) []snapshotCase {
+	activePill, linked, _ := drbreakboard.MakeLinkedPillSpaces(drbreakboard.Right, drbreakboard.Yellow, drbreakboard.Red)
+	nextPill, nextLinked, _ := drbreakboard.MakeLinkedPillSpaces(drbreakboard.Right, drbreakboard.Blue, drbreakboard.Blue)
+	pill := [2]drbreakboard.Space{activePill, linked}
+	next := [2]drbreakboard.Space{nextPill, nextLinked}
+
+	return []snapshotCase{
+		{"waiting", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawWaitingPlayerToImage(target, "Player 1", 10, match.SpeedMed, false)
+		}},
+		{"waiting-ready", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawWaitingPlayerToImage(target, "Player 1", 20, match.SpeedHi, true)
+		}},
+		{"board", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.UpdateBoard(bf.Playfield, pill, [2]int{1, 3})
+			viz.DrawBoardToImage(target)
+		}},
+		{"status", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawStatusToImage(target, 12, next, false)
+		}},
+		{"status-drops", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawStatusToImage(target, 3, next, true)
+		}},
+		{"paused", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawPausedToImage(target, true)
+		}},
+		{"paused-other", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawPausedToImage(target, false)
+		}},
+		{"result-winner", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawResultToImage(target, true, false)
+		}},
+		{"result-champ", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawResultToImage(target, true, true)
+		}},
+		{"result-loser", func(viz *playfieldViz, target *ebiten.Image) {
+			viz.DrawResultToImage(target, false, false)
+		}},
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	images, err := assets.LoadBuiltinImages("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	atlas := NewSpriteAtlas(withColorVariants(images))
+	fonts := fontMap{"base": BaseTextFont, "small": SmallTextFont}
+
+	bf, err := match.ParseBoard(strings.NewReader(snapshotBoardText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *updateGoldens {
+		if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sc := range snapshotCases(bf) {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			viz := NewPlayfieldViz(atlas, fonts)
+			viz.SetPixelSizeAndOffset(snapshotWidth, snapshotHeight, 0, 0)
+
+			target := ebiten.NewImage(snapshotWidth, snapshotHeight)
+			defer target.Dispose()
+			target.Fill(color.Black)
+			sc.draw(viz, target)
+			actual := readImage(target)
+
+			if *updateGoldens {
+				if err := boardrender.WritePNG(filepath.Join(goldenDir, sc.name+".png"), actual); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			checkSnapshot(t, sc.name, actual)
+		})
+	}
+}
+
+func readImage(source *ebiten.Image) *image.RGBA {
+	width, height := source.Size()
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	source.ReadPixels(out.Pix)
+	return out
+}
+
+// compare with the golden, writing the actual image and a diff for a failure
+func checkSnapshot(t *testing.T, name string, actual *image.RGBA) {
+	t.Helper()
+	golden, err := assets.DecodeImageFile(os.DirFS(goldenDir), name+".png")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("no golden for %s, run go test -run TestSnapshots -update to make one", name)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if golden.Bounds().Size() != actual.Bounds().Size() {
+		t.Fatalf("size is %v, golden is %v", actual.Bounds().Size(), golden.Bounds().Size())
+	}
+
+	diff, different := diffImages(golden, actual, snapshotTolerance)
+	total := actual.Bounds().Dx() * actual.Bounds().Dy()
+	if float64(different) <= snapshotMaxDiff*float64(total) {
+		return
+	}
+
+	if err := os.MkdirAll(snapshotFailDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	actualPath := filepath.Join(snapshotFailDir, name+".actual.png")
+	if err := boardrender.WritePNG(actualPath, actual); err != nil {
+		t.Fatal(err)
+	}
+	diffPath := filepath.Join(snapshotFailDir, name+".diff.png")
+	if err := boardrender.WritePNG(diffPath, diff); err != nil {
+		t.Fatal(err)
+	}
+	t.Errorf("%d of %d pixels differ, see %s and %s", different, total, actualPath, diffPath)
+}
+
+// red where the images differ by more than tolerance, a dim copy of the golden elsewhere
+func diffImages(golden image.Image, actual *image.RGBA, tolerance int) (*image.RGBA, int) {
+	bounds := actual.Bounds()
+	goldenMin := golden.Bounds().Min
+	diff := image.NewRGBA(bounds)
+	different := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			want := color.RGBAModel.Convert(golden.At(goldenMin.X+x, goldenMin.Y+y)).(color.RGBA)
+			got := actual.RGBAAt(x, y)
+
+			if channelDiff(want.R, got.R) > tolerance || channelDiff(want.G, got.G) > tolerance ||
+				channelDiff(want.B, got.B) > tolerance || channelDiff(want.A, got.A) > tolerance {
+				diff.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				different++
+				continue
+			}
+			diff.SetRGBA(x, y, color.RGBA{want.R / 4, want.G / 4, want.B / 4, 255})
+		}
+	}
+	return diff, different
+}
+
+func channelDiff(a uint8, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
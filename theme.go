@@ -0,0 +1,212 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"example.com/drbreaktime/assets"
+	"github.com/hajimehoshi/ebiten/v2"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// themes live in their own folder in the config dir, one directory or zip each
+const themesDirName = "themes"
+const themeManifestName = "theme.json"
+const builtinThemeName = "Default"
+
+// sprites a theme can replace, anything it leaves out comes from the built-in theme
+// the background has no built-in sprite and is only drawn when a theme has one
+var themeSpriteNames = []string{
+	"redVirus", "blueVirus", "yellowVirus",
+	"redSingle", "blueSingle", "yellowSingle",
+	"redLinked", "blueLinked", "yellowLinked",
+	"border", "background",
+}
+
+// theme.json at the top of the theme
+type themeManifest struct {
+	Name    string            `json:"name"`
+	Sprites map[string]string `json:"sprites"` // sprite name to image file in the theme
+	Font    string            `json:"font"`    // ttf file in the theme, empty keeps the built-in font
+}
+
+type theme struct {
+	name     string
+	path     string // directory or zip, empty for the built-in theme
+	images   imageMap
+	fontData []byte // nil for the built-in font
+}
+
+// load a theme from a directory or zip holding a manifest
+// sprites that won't load are logged and left to the built-in theme
+func loadTheme(path string) (*theme, error) {
+	fsys, closer, err := openThemeFS(path)
+	if err != nil {
+		return nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	data, err := fs.ReadFile(fsys, themeManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest themeManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Name == "" {
+		manifest.Name = strings.TrimSuffix(filepath.Base(path), ".zip")
+	}
+
+	t := &theme{name: manifest.Name, path: path, images: imageMap{}}
+	for sprite, file := range manifest.Sprites {
+		if !isThemeSprite(sprite) {
+			zlog.Printf("theme %s: unknown sprite %s", t.name, sprite)
+			continue
+		}
+
+		img, err := assets.DecodeImageFile(fsys, file)
+		if err != nil {
+			zlog.Printf("theme %s: could not load %s: %v", t.name, sprite, err)
+			continue
+		}
+		t.images[sprite] = img
+	}
+
+	if manifest.Font != "" {
+		t.fontData, err = fs.ReadFile(fsys, manifest.Font)
+		if err != nil {
+			zlog.Printf("theme %s: could not load font: %v", t.name, err)
+			t.fontData = nil
+		}
+	}
+
+	return t, nil
+}
+
+type themeCloser interface {
+	Close() error
+}
+
+// zips are read in place, anything else is treated as a directory
+func openThemeFS(path string) (fs.FS, themeCloser, error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return reader, reader, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("%s is not a directory or zip", path)
+	}
+	return os.DirFS(path), nil, nil
+}
+
+func isThemeSprite(sprite string) bool {
+	for _, name := range themeSpriteNames {
+		if name == sprite {
+			return true
+		}
+	}
+	return false
+}
+
+// every theme in the themes folder, sorted by name
+// broken themes are logged and skipped
+func findThemes() []*theme {
+	themes := make([]*theme, 0)
+
+	dir, err := configFilePath(themesDirName)
+	if err != nil {
+		zlog.Printf("no config dir for themes: %v", err)
+		return themes
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return themes
+	}
+	if err != nil {
+		zlog.Printf("could not read themes: %v", err)
+		return themes
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.EqualFold(filepath.Ext(entry.Name()), ".zip") {
+			continue
+		}
+
+		t, err := loadTheme(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			zlog.Printf("could not load theme %s: %v", entry.Name(), err)
+			continue
+		}
+		themes = append(themes, t)
+	}
+
+	sort.Slice(themes, func(i, j int) bool { return themes[i].name < themes[j].name })
+	return themes
+}
+
+// switch to the theme at index in g.themes, 0 is the built-in theme
+func (g *Game) applyTheme(index int) {
+	t := g.themes[index]
+
+	// start from the built-in sprites so missing ones fall back
+	for sprite := range g.imageMap {
+		delete(g.imageMap, sprite)
+	}
+	for sprite, img := range g.themes[0].images {
+		g.imageMap[sprite] = img
+	}
+	for sprite, img := range t.images {
+		g.imageMap[sprite] = img
+	}
+	g.atlas.Rebuild(withColorVariants(g.imageMap))
+
+	fontData := assets.BaseTextTTF
+	if t.fontData != nil {
+		fontData = t.fontData
+	}
+	if err := setTextFont(fontData); err != nil {
+		zlog.Printf("theme %s: bad font, using the built-in one: %v", t.name, err)
+		_ = setTextFont(assets.BaseTextTTF)
+	}
+	g.fontMap["base"] = BaseTextFont
+	g.fontMap["small"] = SmallTextFont
+
+	g.themeIndex = index
+	g.layoutPlayfields()
+	zlog.Printf("theme: %s", t.name)
+}
+
+// the theme's background stretched over the whole screen, if it has one
+func (g *Game) drawBackground(screen *ebiten.Image) {
+	img := g.atlas.Sprite("background")
+	if img == nil {
+		return
+	}
+
+	screenWidth, screenHeight := screen.Size()
+	imgWidth, imgHeight := img.Size()
+
+	geom := ebiten.GeoM{}
+	geom.Scale(float64(screenWidth)/float64(imgWidth), float64(screenHeight)/float64(imgHeight))
+	screen.DrawImage(img, &ebiten.DrawImageOptions{GeoM: geom})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// fake controller id for touches and the mouse, below the keyboard controllers
+const touchControllerId = keyboardControllerId - 1
+
+// the mouse acts like one more finger
+const mouseTouchID = ebiten.TouchID(-1)
+
+// pixels a finger moves for one step left, right or up, or to start holding down
+const swipeStep = 24
+
+// a touch let go within this many ticks without moving is a tap
+const tapTicks = 15
+
+// on screen buttons for the actions gestures don't cover
+type touchButton struct {
+	label  string
+	action match.InputAction
+	rect   image.Rectangle // from the bottom right corner of the screen
+}
+
+var touchButtons = []touchButton{
+	{"Back", match.ActionSelect, image.Rect(-180, -32, -124, -4)},
+	{"B", match.ActionSecondary, image.Rect(-120, -32, -64, -4)},
+	{"Start", match.ActionStart, image.Rect(-60, -32, -4, -4)},
+}
+
+// where the button is on a width x height screen
+func (button touchButton) screenRect(width int, height int) image.Rectangle {
+	return button.rect.Add(image.Pt(width, height))
+}
+
+// swipes move, tap rotates, the buttons do the rest
+type touchDriver struct {
+	active       bool // touched at least once, the buttons only show after that
+	screenWidth  int
+	screenHeight int
+	touchIDsBuf  []ebiten.TouchID
+	buttonPushes map[ebiten.TouchID]match.InputAction // touches holding a button
+
+	// the touch doing gestures
+	swiping        bool
+	swipeID        ebiten.TouchID
+	originX        int // where the next step is measured from
+	originY        int
+	swipeTicks     int
+	swipeMoved     bool
+	held           [match.InputActionCount]bool // actions held last frame
+	pendingPressed [match.InputActionCount]bool // steps waiting on a release tick
+}
+
+func NewTouchDriver() *touchDriver {
+	td := &touchDriver{screenWidth: baseScreenWidth, screenHeight: baseScreenHeight}
+	td.buttonPushes = map[ebiten.TouchID]match.InputAction{}
+	return td
+}
+
+func (driver *touchDriver) DeviceId() int {
+	return touchControllerId
+}
+
+func (driver *touchDriver) Kind() match.DeviceKind {
+	return match.TouchDevice
+}
+
+func (driver *touchDriver) Poll(tick int) []match.InputEvent {
+	pressed := [match.InputActionCount]bool{}
+
+	// new touches either push a button or start a swipe
+	driver.touchIDsBuf = inpututil.AppendJustPressedTouchIDs(driver.touchIDsBuf[:0])
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		driver.touchIDsBuf = append(driver.touchIDsBuf, mouseTouchID)
+	}
+	for _, id := range driver.touchIDsBuf {
+		driver.active = true
+		x, y := touchPosition(id)
+		if button, onButton := driver.buttonAt(x, y); onButton {
+			driver.buttonPushes[id] = button.action
+			continue
+		}
+		if !driver.swiping {
+			driver.swiping = true
+			driver.swipeID = id
+			driver.originX, driver.originY = x, y
+			driver.swipeTicks = 0
+			driver.swipeMoved = false
+		}
+	}
+
+	for id, action := range driver.buttonPushes {
+		if !isTouchDown(id) {
+			delete(driver.buttonPushes, id)
+			continue
+		}
+		pressed[action] = true
+	}
+
+	if driver.swiping {
+		driver.updateSwipe(&pressed)
+	}
+
+	return match.ActionsToInputEvents(driver, tick, pressed, &driver.held)
+}
+
+func (driver *touchDriver) updateSwipe(pressed *[match.InputActionCount]bool) {
+	if !isTouchDown(driver.swipeID) {
+		driver.swiping = false
+		driver.pendingPressed = [match.InputActionCount]bool{}
+		if !driver.swipeMoved && driver.swipeTicks < tapTicks {
+			pressed[match.ActionPrimary] = true
+		}
+		return
+	}
+	driver.swipeTicks++
+
+	// any step means this touch isn't a tap
+	x, y := touchPosition(driver.swipeID)
+	if x-driver.originX >= swipeStep {
+		driver.originX += swipeStep
+		driver.pendingPressed[match.ActionRight] = true
+		driver.swipeMoved = true
+	} else if driver.originX-x >= swipeStep {
+		driver.originX -= swipeStep
+		driver.pendingPressed[match.ActionLeft] = true
+		driver.swipeMoved = true
+	}
+	if driver.originY-y >= swipeStep {
+		driver.originY -= swipeStep
+		driver.pendingPressed[match.ActionUp] = true
+		driver.swipeMoved = true
+	}
+
+	// each step is its own press, so wait a tick after the last one came up
+	for _, action := range []match.InputAction{match.ActionLeft, match.ActionRight, match.ActionUp} {
+		if driver.pendingPressed[action] && !driver.held[action] {
+			pressed[action] = true
+			driver.pendingPressed[action] = false
+		}
+	}
+
+	// down holds for as long as the finger stays pulled down
+	if y-driver.originY >= swipeStep {
+		pressed[match.ActionDown] = true
+		driver.swipeMoved = true
+	}
+}
+
+// true once the player has touched or clicked
+func (driver *touchDriver) Active() bool {
+	return driver.active
+}
+
+// the buttons follow the corner when the window changes size
+func (driver *touchDriver) SetScreenSize(width int, height int) {
+	driver.screenWidth, driver.screenHeight = width, height
+}
+
+func touchPosition(id ebiten.TouchID) (int, int) {
+	if id == mouseTouchID {
+		return ebiten.CursorPosition()
+	}
+	return ebiten.TouchPosition(id)
+}
+
+func isTouchDown(id ebiten.TouchID) bool {
+	if id == mouseTouchID {
+		return ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	}
+	return inpututil.TouchPressDuration(id) > 0
+}
+
+func (driver *touchDriver) buttonAt(x, y int) (touchButton, bool) {
+	for _, button := range touchButtons {
+		if image.Pt(x, y).In(button.screenRect(driver.screenWidth, driver.screenHeight)) {
+			return button, true
+		}
+	}
+	return touchButton{}, false
+}
+
+func (g *Game) drawTouchButtons(screen *ebiten.Image) {
+	if !g.inputDriver.touchDriver.Active() {
+		return
+	}
+
+	pxImage := g.atlas.Sprite("border")
+	for _, button := range touchButtons {
+		rect := button.screenRect(screen.Size())
+		geom := stretchedGeoM(pxImage, float64(rect.Dx()), float64(rect.Dy()))
+		geom.Translate(float64(rect.Min.X), float64(rect.Min.Y))
+		colorM := ebiten.ColorM{}
+		colorM.Scale(1, 1, 1, 0.4)
+		screen.DrawImage(pxImage, &ebiten.DrawImageOptions{GeoM: geom, ColorM: colorM})
+
+		text.Draw(screen, button.label, SmallTextFont, rect.Min.X+6, rect.Max.Y-8,
+			color.RGBA{128, 128, 128, 255})
+	}
+}
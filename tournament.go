@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+type TournamentFormat int
+
+const (
+	SingleElimination TournamentFormat = iota
+	DoubleElimination
+	RoundRobin
+	tournamentFormatCount
+)
+
+var tournamentFormatNames = [...]string{"Single Elim", "Double Elim", "Round Robin"}
+
+const tournamentFileName = "tournament.json"
+const tournamentFileVersion = 1
+
+// bracket labels for matches
+const (
+	winnersBracket = "W"
+	losersBracket  = "L"
+	grandFinal     = "F"
+	roundRobinPool = "RR"
+)
+
+type tournamentMatch struct {
+	Round   int       `json:"round"`
+	Bracket string    `json:"bracket"`
+	Players [2]string `json:"players"`
+	Winner  string    `json:"winner"` // empty until the match is played
+}
+
+// elimination brackets are built a round at a time from everyone's loss count,
+// so a player is out after one loss in single elimination and two in double
+type tournament struct {
+	Version  int                `json:"version"`
+	Format   TournamentFormat   `json:"format"`
+	Players  []string           `json:"players"`
+	Losses   map[string]int     `json:"losses"`
+	Wins     map[string]int     `json:"wins"`
+	Byes     map[string]int     `json:"byes"`
+	Matches  []*tournamentMatch `json:"matches"`
+	Round    int                `json:"round"`
+	Champion string             `json:"champion"` // empty until the tournament is over
+}
+
+func NewTournament(format TournamentFormat, players []string) (*tournament, error) {
+	if len(players) < 2 {
+		return nil, errors.New("tournament needs two players")
+	}
+
+	t := &tournament{Version: tournamentFileVersion, Format: format}
+	t.Players = append([]string{}, players...)
+	t.Losses = make(map[string]int)
+	t.Wins = make(map[string]int)
+	t.Byes = make(map[string]int)
+	t.Matches = make([]*tournamentMatch, 0)
+
+	if format == RoundRobin {
+		t.scheduleRoundRobin()
+	} else {
+		t.startNextRound()
+	}
+
+	return t, nil
+}
+
+func (t *tournament) IsFinished() bool {
+	return t.Champion != ""
+}
+
+// first match without a winner, nil when the tournament is over
+func (t *tournament) NextMatch() *tournamentMatch {
+	for _, match := range t.Matches {
+		if match.Winner == "" {
+			return match
+		}
+	}
+	return nil
+}
+
+// index of the next match in Matches, len(Matches) when everything is played
+func (t *tournament) NextMatchIndex() int {
+	for i, match := range t.Matches {
+		if match.Winner == "" {
+			return i
+		}
+	}
+	return len(t.Matches)
+}
+
+// record the winner of the next match and move the bracket on
+func (t *tournament) RecordResult(winner string) error {
+	match := t.NextMatch()
+	if match == nil {
+		return errors.New("no match to record")
+	}
+
+	if winner != match.Players[0] && winner != match.Players[1] {
+		return fmt.Errorf("%s is not in the match", winner)
+	}
+
+	loser := match.Players[0]
+	if loser == winner {
+		loser = match.Players[1]
+	}
+
+	match.Winner = winner
+	t.Wins[winner]++
+	t.Losses[loser]++
+
+	// round over, set up the next one
+	if t.NextMatch() == nil {
+		t.startNextRound()
+	}
+
+	return nil
+}
+
+// players with the given number of losses, in seeding order
+func (t *tournament) playersWithLosses(losses int) []string {
+	players := make([]string, 0)
+	for _, player := range t.Players {
+		if t.Losses[player] == losses {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+func (t *tournament) startNextRound() {
+	if t.Format == RoundRobin {
+		t.finishRoundRobin()
+		return
+	}
+
+	t.Round++
+
+	winners := t.playersWithLosses(0)
+	losers := make([]string, 0)
+	if t.Format == DoubleElimination {
+		losers = t.playersWithLosses(1)
+	}
+
+	switch {
+	case len(winners)+len(losers) == 1:
+		// one player left standing
+		t.Champion = append(winners, losers...)[0]
+	case len(winners) == 1 && len(losers) == 1:
+		t.Matches = append(t.Matches, &tournamentMatch{
+			Round: t.Round, Bracket: grandFinal, Players: [2]string{winners[0], losers[0]}})
+	case len(winners) == 0 && len(losers) == 2:
+		// the losers bracket player took the grand final, the reset decides it
+		t.Matches = append(t.Matches, &tournamentMatch{
+			Round: t.Round, Bracket: grandFinal, Players: [2]string{losers[0], losers[1]}})
+	default:
+		// a lone player in either bracket waits for the other bracket to catch up
+		if len(winners) >= 2 {
+			t.pairPlayers(winners, winnersBracket)
+		}
+		if len(losers) >= 2 {
+			t.pairPlayers(losers, losersBracket)
+		}
+	}
+}
+
+// pair players off in order, the bye goes to whoever has had the fewest
+func (t *tournament) pairPlayers(players []string, bracket string) {
+	if len(players)%2 == 1 {
+		byeIndex := len(players) - 1
+		for i := len(players) - 1; i >= 0; i-- {
+			if t.Byes[players[i]] < t.Byes[players[byeIndex]] {
+				byeIndex = i
+			}
+		}
+		t.Byes[players[byeIndex]]++
+
+		remaining := append([]string{}, players[:byeIndex]...)
+		players = append(remaining, players[byeIndex+1:]...)
+	}
+
+	for i := 0; i+1 < len(players); i += 2 {
+		t.Matches = append(t.Matches, &tournamentMatch{
+			Round: t.Round, Bracket: bracket, Players: [2]string{players[i], players[i+1]}})
+	}
+}
+
+// everyone plays everyone once, scheduled with the circle method
+// so nobody plays twice in a round
+func (t *tournament) scheduleRoundRobin() {
+	circle := append([]string{}, t.Players...)
+	if len(circle)%2 == 1 {
+		circle = append(circle, "") // empty slot is a bye
+	}
+
+	for round := 1; round < len(circle); round++ {
+		for i := 0; i < len(circle)/2; i++ {
+			a, b := circle[i], circle[len(circle)-1-i]
+			if a != "" && b != "" {
+				t.Matches = append(t.Matches, &tournamentMatch{
+					Round: round, Bracket: roundRobinPool, Players: [2]string{a, b}})
+			}
+		}
+
+		// keep the first player fixed and rotate everyone else
+		last := circle[len(circle)-1]
+		copy(circle[2:], circle[1:len(circle)-1])
+		circle[1] = last
+	}
+
+	t.Round = 1
+}
+
+// most wins takes it, ties go to the higher seed
+func (t *tournament) finishRoundRobin() {
+	best := t.Players[0]
+	for _, player := range t.Players {
+		if t.Wins[player] > t.Wins[best] {
+			best = player
+		}
+	}
+	t.Champion = best
+}
+
+func tournamentFilePath() (string, error) {
+	return configFilePath(tournamentFileName)
+}
+
+// load the saved tournament, nil if there isn't one
+func loadTournament(filePath string) (*tournament, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tournament{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	if t.Version > tournamentFileVersion {
+		return nil, fmt.Errorf("tournament file version %d is newer than this game", t.Version)
+	}
+
+	if len(t.Players) < 2 {
+		return nil, fmt.Errorf("%s: tournament has too few players", filePath)
+	}
+
+	if t.Losses == nil {
+		t.Losses = make(map[string]int)
+	}
+	if t.Wins == nil {
+		t.Wins = make(map[string]int)
+	}
+	if t.Byes == nil {
+		t.Byes = make(map[string]int)
+	}
+
+	return t, nil
+}
+
+func (t *tournament) Save(filePath string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filePath, data)
+}
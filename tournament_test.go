@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// a match as round, bracket and players, for comparing brackets
+type bracketEntry struct {
+	round   int
+	bracket string
+	players [2]string
+}
+
+func bracketEntries(matches []*tournamentMatch) []bracketEntry {
+	entries := make([]bracketEntry, 0, len(matches))
+	for _, match := range matches {
+		entries = append(entries, bracketEntry{match.Round, match.Bracket, match.Players})
+	}
+	return entries
+}
+
+// play the winners in order, failing if any can't be recorded
+func recordWinners(t *testing.T, tm *tournament, winners ...string) {
+	t.Helper()
+	for _, winner := range winners {
+		if err := tm.RecordResult(winner); err != nil {
+			t.Fatalf("recording %s: %v", winner, err)
+		}
+	}
+}
+
+func TestSingleElimination(t *testing.T) {
+	tm, err := NewTournament(SingleElimination, []string{"a", "b", "c", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordWinners(t, tm, "a", "d")
+	if tm.IsFinished() {
+		t.Fatal("finished after the first round")
+	}
+	recordWinners(t, tm, "d")
+
+	want := []bracketEntry{
+		{1, winnersBracket, [2]string{"a", "b"}},
+		{1, winnersBracket, [2]string{"c", "d"}},
+		{2, winnersBracket, [2]string{"a", "d"}},
+	}
+	if got := bracketEntries(tm.Matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("matches are %v, want %v", got, want)
+	}
+	if tm.Champion != "d" {
+		t.Errorf("champion is %q, want d", tm.Champion)
+	}
+}
+
+// the odd player out sits a round, and not the same one twice running
+func TestSingleEliminationByes(t *testing.T) {
+	tm, err := NewTournament(SingleElimination, []string{"a", "b", "c", "d", "e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordWinners(t, tm, "a", "c", "e")
+
+	want := []bracketEntry{
+		{1, winnersBracket, [2]string{"a", "b"}},
+		{1, winnersBracket, [2]string{"c", "d"}},
+		{2, winnersBracket, [2]string{"a", "e"}},
+		{3, winnersBracket, [2]string{"c", "e"}},
+	}
+	if got := bracketEntries(tm.Matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("matches are %v, want %v", got, want)
+	}
+	if tm.Byes["e"] != 1 || tm.Byes["c"] != 1 {
+		t.Errorf("byes are %v, want one each for c and e", tm.Byes)
+	}
+}
+
+func TestDoubleElimination(t *testing.T) {
+	tm, err := NewTournament(DoubleElimination, []string{"a", "b", "c", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordWinners(t, tm, "a", "c", "a", "b", "c", "a")
+
+	want := []bracketEntry{
+		{1, winnersBracket, [2]string{"a", "b"}},
+		{1, winnersBracket, [2]string{"c", "d"}},
+		{2, winnersBracket, [2]string{"a", "c"}},
+		{2, losersBracket, [2]string{"b", "d"}},
+		{3, losersBracket, [2]string{"b", "c"}},
+		{4, grandFinal, [2]string{"a", "c"}},
+	}
+	if got := bracketEntries(tm.Matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("matches are %v, want %v", got, want)
+	}
+	if tm.Champion != "a" {
+		t.Errorf("champion is %q, want a", tm.Champion)
+	}
+}
+
+// the losers bracket player winning the grand final forces a second one
+func TestDoubleEliminationBracketReset(t *testing.T) {
+	tm, err := NewTournament(DoubleElimination, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordWinners(t, tm, "a", "b")
+	if tm.IsFinished() {
+		t.Fatal("finished before the bracket reset")
+	}
+	recordWinners(t, tm, "b")
+
+	want := []bracketEntry{
+		{1, winnersBracket, [2]string{"a", "b"}},
+		{2, grandFinal, [2]string{"a", "b"}},
+		{3, grandFinal, [2]string{"a", "b"}},
+	}
+	if got := bracketEntries(tm.Matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("matches are %v, want %v", got, want)
+	}
+	if tm.Champion != "b" {
+		t.Errorf("champion is %q, want b", tm.Champion)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	players := []string{"a", "b", "c", "d", "e"}
+	tm, err := NewTournament(RoundRobin, players)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// everyone plays everyone once and nobody plays twice in a round
+	played := make(map[[2]string]bool)
+	inRound := make(map[int]map[string]bool)
+	for _, match := range tm.Matches {
+		if match.Bracket != roundRobinPool {
+			t.Errorf("match is in bracket %q, want %q", match.Bracket, roundRobinPool)
+		}
+		pair := match.Players
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		if played[pair] {
+			t.Errorf("%s and %s play twice", pair[0], pair[1])
+		}
+		played[pair] = true
+
+		if inRound[match.Round] == nil {
+			inRound[match.Round] = make(map[string]bool)
+		}
+		for _, player := range match.Players {
+			if inRound[match.Round][player] {
+				t.Errorf("%s plays twice in round %d", player, match.Round)
+			}
+			inRound[match.Round][player] = true
+		}
+	}
+	if want := len(players) * (len(players) - 1) / 2; len(played) != want {
+		t.Errorf("%d pairs play, want %d", len(played), want)
+	}
+
+	// c wins every match it's in, the rest go to the first player listed
+	for !tm.IsFinished() {
+		winner := tm.NextMatch().Players[0]
+		if tm.NextMatch().Players[1] == "c" {
+			winner = "c"
+		}
+		recordWinners(t, tm, winner)
+	}
+	if tm.Wins["c"] != len(players)-1 {
+		t.Errorf("c won %d, want %d", tm.Wins["c"], len(players)-1)
+	}
+	if tm.Champion != "c" {
+		t.Errorf("champion is %q, want c", tm.Champion)
+	}
+}
+
+func TestRecordResultChecksPlayers(t *testing.T) {
+	tm, err := NewTournament(SingleElimination, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.RecordResult("c"); err == nil {
+		t.Error("recorded a win for a player not in the match")
+	}
+	recordWinners(t, tm, "a")
+	if err := tm.RecordResult("a"); err == nil {
+		t.Error("recorded a result after the tournament finished")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+
+	"example.com/drbreaktime/match"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+const tournamentSetupRows = 10
+const bracketRows = 16
+
+// choices on the tournament setup screen
+type tournamentSetup struct {
+	cursor   int
+	selected map[string]bool
+	format   TournamentFormat
+}
+
+func NewTournamentSetup() *tournamentSetup {
+	setup := &tournamentSetup{format: SingleElimination}
+	setup.selected = make(map[string]bool)
+	return setup
+}
+
+// resume the saved tournament if there is one, otherwise set up a new one
+func (g *Game) enterTournament() {
+	g.tournamentMessage = ""
+
+	if g.tournament == nil {
+		filePath, err := tournamentFilePath()
+		if err == nil {
+			g.tournament, err = loadTournament(filePath)
+		}
+		if err != nil {
+			g.tournamentMessage = err.Error()
+		}
+	}
+
+	if g.tournament != nil {
+		g.currentStage = TournamentBracket
+		return
+	}
+
+	g.tournamentSetup = NewTournamentSetup()
+	g.currentStage = TournamentSetup
+}
+
+func (g *Game) saveTournament() {
+	filePath, err := tournamentFilePath()
+	if err == nil {
+		err = g.tournament.Save(filePath)
+	}
+	if err != nil {
+		g.tournamentMessage = "could not save: " + err.Error()
+	}
+}
+
+// drop the tournament and its save file
+func (g *Game) clearTournament() {
+	g.tournament = nil
+
+	filePath, err := tournamentFilePath()
+	if err != nil {
+		return
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		g.tournamentMessage = "could not delete: " + err.Error()
+	}
+}
+
+func (g *Game) updateTournamentSetup(buttonPressEvents map[int][]match.GamepadEvent) {
+	setup := g.tournamentSetup
+	names := g.profileStore.Names()
+
+	for _, events := range buttonPressEvents {
+		for _, event := range events {
+			switch event {
+			case match.UpJustPressed:
+				if len(names) > 0 {
+					setup.cursor = (setup.cursor + len(names) - 1) % len(names)
+				}
+			case match.DownJustPressed:
+				if len(names) > 0 {
+					setup.cursor = (setup.cursor + 1) % len(names)
+				}
+			case match.LeftJustPressed:
+				setup.format = (setup.format + tournamentFormatCount - 1) % tournamentFormatCount
+			case match.RightJustPressed:
+				setup.format = (setup.format + 1) % tournamentFormatCount
+			case match.PrimaryJustPressed:
+				if len(names) > 0 {
+					setup.selected[names[setup.cursor]] = !setup.selected[names[setup.cursor]]
+				}
+			case match.StartJustPressed:
+				// seed players in profile order
+				players := make([]string, 0)
+				for _, name := range names {
+					if setup.selected[name] {
+						players = append(players, name)
+					}
+				}
+
+				t, err := NewTournament(setup.format, players)
+				if err != nil {
+					g.tournamentMessage = err.Error()
+					continue
+				}
+
+				g.tournament = t
+				g.tournamentMessage = ""
+				g.saveTournament()
+				g.currentStage = TournamentBracket
+				return
+			case match.SelectJustPressed:
+				g.ResetGame()
+				return
+			}
+		}
+	}
+}
+
+func (g *Game) updateTournamentBracket(buttonPressEvents map[int][]match.GamepadEvent) {
+	for _, events := range buttonPressEvents {
+		for _, event := range events {
+			switch event {
+			case match.StartJustPressed:
+				if g.tournament.IsFinished() {
+					g.clearTournament()
+					g.ResetGame()
+					return
+				}
+
+				// call the next two players up
+				g.tournamentMatch = g.tournament.NextMatch()
+				g.tournamentAbandon = false
+				g.playerCount = 0
+				g.currentStage = PlayerAssignment
+				return
+			case match.SecondaryJustPressed:
+				// takes a second press so a stray button doesn't wipe the bracket
+				if !g.tournamentAbandon {
+					g.tournamentAbandon = true
+					g.tournamentMessage = "Press B again to abandon"
+					continue
+				}
+				g.clearTournament()
+				g.ResetGame()
+				return
+			case match.SelectJustPressed:
+				// the bracket stays saved for later
+				g.ResetGame()
+				return
+			}
+		}
+	}
+}
+
+// record the winner of a finished tournament match
+func (g *Game) recordTournamentResult() {
+	if g.tournamentMatch == nil || g.tournament == nil {
+		return
+	}
+
+	setup, exists := g.playerSetups[g.matchDriver.Winner]
+	if !exists {
+		return
+	}
+
+	if err := g.tournament.RecordResult(setup.profileName); err != nil {
+		g.tournamentMessage = err.Error()
+		return
+	}
+	g.saveTournament()
+}
+
+func (g *Game) drawTournamentSetup(screen *ebiten.Image) {
+	textColor := color.RGBA{128, 128, 128, 255}
+	setup := g.tournamentSetup
+
+	text.Draw(screen, "Tournament", BaseTextFont, 100, 50, textColor)
+	text.Draw(screen, "< "+tournamentFormatNames[setup.format]+" >", BaseTextFont, 380, 50, textColor)
+	text.Draw(screen, "A: pick player   Left/Right: format   Start: begin", SmallTextFont, 100, 80, textColor)
+
+	names := g.profileStore.Names()
+	if len(names) == 0 {
+		text.Draw(screen, "Make some profiles first", BaseTextFont, 100, 130, textColor)
+	}
+
+	// scroll so the cursor stays on screen
+	first := 0
+	if setup.cursor >= tournamentSetupRows {
+		first = setup.cursor - tournamentSetupRows + 1
+	}
+
+	for i := first; i < len(names) && i < first+tournamentSetupRows; i++ {
+		marker := "  "
+		if i == setup.cursor {
+			marker = "> "
+		}
+		picked := "[ ]"
+		if setup.selected[names[i]] {
+			picked = "[x]"
+		}
+
+		y := 130 + (i-first)*30
+		text.Draw(screen, marker+picked+" "+names[i], BaseTextFont, 100, y, textColor)
+	}
+
+	text.Draw(screen, g.tournamentMessage, SmallTextFont, 100, 460, textColor)
+}
+
+func (g *Game) drawTournamentBracket(screen *ebiten.Image) {
+	textColor := color.RGBA{128, 128, 128, 255}
+	t := g.tournament
+
+	text.Draw(screen, "Tournament - "+tournamentFormatNames[t.Format], BaseTextFont, 60, 40, textColor)
+
+	if t.IsFinished() {
+		text.Draw(screen, "Champion: "+t.Champion, BaseTextFont, 60, 75, textColor)
+		text.Draw(screen, "Start: finish   Select: back", SmallTextFont, 60, 100, textColor)
+	} else {
+		next := t.NextMatch()
+		text.Draw(screen, "Next: "+next.Players[0]+" vs "+next.Players[1], BaseTextFont, 60, 75, textColor)
+		text.Draw(screen, "Start: play   B: abandon   Select: back", SmallTextFont, 60, 100, textColor)
+	}
+
+	// keep the next match in view with a few played ones above it
+	nextIndex := t.NextMatchIndex()
+	first := nextIndex - bracketRows/2
+	if first > len(t.Matches)-bracketRows {
+		first = len(t.Matches) - bracketRows
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	for i := first; i < len(t.Matches) && i < first+bracketRows; i++ {
+		match := t.Matches[i]
+
+		marker := "  "
+		if i == nextIndex {
+			marker = "> "
+		}
+		result := ""
+		if match.Winner != "" {
+			result = "won by " + match.Winner
+		}
+
+		y := 130 + (i-first)*20
+		text.Draw(screen, fmt.Sprintf("%s%s%d", marker, match.Bracket, match.Round), SmallTextFont, 60, y, textColor)
+		text.Draw(screen, match.Players[0]+" vs "+match.Players[1], SmallTextFont, 140, y, textColor)
+		text.Draw(screen, result, SmallTextFont, 380, y, textColor)
+	}
+
+	// round robin is decided on wins, so show the table
+	if t.Format == RoundRobin {
+		standings := "Wins:"
+		for _, player := range t.Players {
+			standings += fmt.Sprintf(" %s %d", player, t.Wins[player])
+		}
+		text.Draw(screen, standings, SmallTextFont, 60, 445, textColor)
+	}
+
+	text.Draw(screen, g.tournamentMessage, SmallTextFont, 60, 465, textColor)
+}